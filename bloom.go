@@ -0,0 +1,133 @@
+package bwarr
+
+import (
+	"math"
+	"math/bits"
+)
+
+// SegmentFilter is a probabilistic filter kept for segments to skip them in searches of missing elements.
+// Use BloomFilter to create one for Options.Filter.
+type SegmentFilter interface {
+	segmentFilter()
+}
+
+const maxBloomHashes = 16
+
+// BloomFilter returns a SegmentFilter that keeps a Bloom filter for every segment of rank minRank and above.
+// Has, Get, Delete and ReplaceOrInsert skip the segments whose filter rules the element out, which speeds up
+// searches of missing elements. hash must return equal values for equal elements (by CmpFunc) and should
+// spread the bits well. bitsPerElement sets the memory to false positive rate tradeoff: 10 bits give about 1%.
+//
+// Filters are rebuilt when a segment is rewritten by a merge, so insertions spend O(1) amortized hash calls
+// per element per rank. Deleted elements stay in filters until their segment is rewritten.
+// Small segments are cheap to search, so minRank of 8 or more is a reasonable choice.
+// Passing the filter to a BWArr of another element type panics.
+func BloomFilter[T any](hash func(T) uint64, minRank, bitsPerElement int) SegmentFilter {
+	if bitsPerElement < 1 {
+		panic("bwarr: bitsPerElement must be positive")
+	}
+	k := int(math.Round(float64(bitsPerElement) * math.Ln2))
+	return &bloomFilters[T]{
+		hash:           hash,
+		minRank:        max(minRank, 0),
+		bitsPerElement: bitsPerElement,
+		hashes:         min(max(k, 1), maxBloomHashes),
+		filters:        nil,
+	}
+}
+
+// bloomFilters is both the configuration passed in Options and the per-BWArr state.
+type bloomFilters[T any] struct {
+	hash           func(T) uint64
+	minRank        int
+	bitsPerElement int
+	hashes         int        // Number of bits set per element.
+	filters        [][]uint64 // Per-rank filters, nil below minRank.
+}
+
+func (*bloomFilters[T]) segmentFilter() {}
+
+// newState returns a copy of the configuration without filters, to be owned by a BWArr.
+func (bf *bloomFilters[T]) newState() *bloomFilters[T] {
+	return &bloomFilters[T]{hash: bf.hash, minRank: bf.minRank, bitsPerElement: bf.bitsPerElement, hashes: bf.hashes, filters: nil}
+}
+
+func (bf *bloomFilters[T]) clone() *bloomFilters[T] {
+	c := bf.newState()
+	c.filters = make([][]uint64, len(bf.filters))
+	for rank, f := range bf.filters {
+		if f != nil {
+			c.filters[rank] = append([]uint64(nil), f...)
+		}
+	}
+	return c
+}
+
+// build rebuilds the filter of the segment of rank from its non-deleted elements.
+func (bf *bloomFilters[T]) build(rank int, seg *segment[T]) {
+	if rank < bf.minRank {
+		return
+	}
+	if rank >= len(bf.filters) {
+		bf.filters = append(bf.filters, make([][]uint64, rank-len(bf.filters)+1)...)
+	}
+	words := ((len(seg.elements)*bf.bitsPerElement + wordBits - 1) / wordBits)
+	f := bf.filters[rank]
+	if len(f) != words {
+		f = make([]uint64, words)
+		bf.filters[rank] = f
+	} else {
+		clear(f)
+	}
+	m := uint64(words * wordBits) //nolint:gosec
+	for i := seg.nextNonDeletedAfter(-1); i < len(seg.elements); i = seg.nextNonDeletedAfter(i) {
+		h1, h2 := splitHash(bf.hash(seg.elements[i]))
+		for j := range uint32(bf.hashes) { //nolint:gosec
+			bit := fastRange(h1+j*h2, m)
+			f[bit/wordBits] |= 1 << (bit % wordBits)
+		}
+	}
+}
+
+// release drops the filter of the released segment of rank.
+func (bf *bloomFilters[T]) release(rank int) {
+	if rank < len(bf.filters) {
+		bf.filters[rank] = nil
+	}
+}
+
+// mayContain returns false if the element with the hash is not in the segment of rank.
+func (bf *bloomFilters[T]) mayContain(rank int, hash uint64) bool {
+	if rank < bf.minRank || rank >= len(bf.filters) || bf.filters[rank] == nil {
+		return true
+	}
+	f := bf.filters[rank]
+	m := uint64(len(f) * wordBits) //nolint:gosec
+	h1, h2 := splitHash(hash)
+	for j := range uint32(bf.hashes) { //nolint:gosec
+		bit := fastRange(h1+j*h2, m)
+		if f[bit/wordBits]&(1<<(bit%wordBits)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (bf *bloomFilters[T]) allocatedBytes() int {
+	n := 0
+	for _, f := range bf.filters {
+		n += cap(f) * wordBits / 8
+	}
+	return n
+}
+
+// splitHash derives two hashes for double hashing, the second one is odd to visit different bits.
+func splitHash(h uint64) (h1, h2 uint32) {
+	return uint32(h), uint32(h>>32) | 1 //nolint:gosec
+}
+
+// fastRange maps x to [0, m) without division.
+func fastRange(x uint32, m uint64) uint64 {
+	hi, _ := bits.Mul64(uint64(x)<<32, m)
+	return hi
+}
@@ -0,0 +1,112 @@
+package bwarr
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pageItemHash hashes only the compared field, so equal elements get equal hashes.
+func pageItemHash(v pageItem) uint64 {
+	return uint64(v.key) * 0x9E3779B97F4A7C15 //nolint:gosec
+}
+
+func int64Hash(v int64) uint64 {
+	return uint64(v) * 0x9E3779B97F4A7C15 //nolint:gosec
+}
+
+func TestBWArr_BloomFilterMatchesPlain(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(17))                        //nolint:gosec
+	opts := Options{Filter: BloomFilter(pageItemHash, 2, 8)} //nolint:exhaustruct
+	filtered, plain := NewWithOptions(pageItemCmp, 0, opts), New(pageItemCmp, 0)
+	var hint Hint
+	for seq := range int64(8000) {
+		v := pageItem{key: r.Int63n(1000), seq: seq}
+		switch r.Intn(6) {
+		case 0, 1:
+			filtered.Insert(v)
+			plain.Insert(v)
+		case 2:
+			got, gotFound := filtered.Delete(v)
+			want, wantFound := plain.Delete(v)
+			require.Equal(t, wantFound, gotFound, "op %d", seq)
+			require.Equal(t, want, got, "op %d", seq)
+		case 3:
+			got, gotFound := filtered.ReplaceOrInsert(v)
+			want, wantFound := plain.ReplaceOrInsert(v)
+			require.Equal(t, wantFound, gotFound, "op %d", seq)
+			require.Equal(t, want, got, "op %d", seq)
+		case 4:
+			got, gotFound := filtered.Get(v)
+			want, wantFound := plain.Get(v)
+			require.Equal(t, wantFound, gotFound, "op %d", seq)
+			require.Equal(t, want, got, "op %d", seq)
+			require.Equal(t, wantFound, filtered.HasHint(v, &hint), "op %d", seq)
+		case 5:
+			if seq%500 == 0 {
+				filtered.Rebuild()
+			}
+		}
+	}
+	require.NoError(t, filtered.Validate())
+
+	clone := filtered.Clone()
+	for key := range int64(1000) {
+		want := plain.Has(pageItem{key: key})                                   //nolint:exhaustruct
+		require.Equal(t, want, filtered.Has(pageItem{key: key}), "key %d", key) //nolint:exhaustruct
+		require.Equal(t, want, clone.Has(pageItem{key: key}), "key %d", key)    //nolint:exhaustruct
+	}
+}
+
+func TestNewFromSliceWithOptions_BloomFilter(t *testing.T) {
+	t.Parallel()
+	slice := make([]int64, 1000)
+	for i := range slice {
+		slice[i] = int64(i * 2)
+	}
+	bwa := NewFromSliceWithOptions(int64Cmp, slice, Options{Filter: BloomFilter(int64Hash, 0, 10)}) //nolint:exhaustruct
+	for i := range int64(2000) {
+		assert.Equal(t, i%2 == 0, bwa.Has(i), "%d", i)
+	}
+	assert.Positive(t, bwa.Stats().BytesAllocated-NewFromSlice(int64Cmp, slice).Stats().BytesAllocated)
+
+	bwa.Clear(true)
+	bwa.Insert(1)
+	assert.True(t, bwa.Has(1))
+	assert.False(t, bwa.Has(2))
+}
+
+func TestBWArr_BloomFilterReducesComparisons(t *testing.T) {
+	t.Parallel()
+	const n = 1<<14 - 1
+	plain := NewWithOptions(int64Cmp, 0, Options{CountComparisons: true}) //nolint:exhaustruct
+	filtered := NewWithOptions(int64Cmp, 0, Options{                      //nolint:exhaustruct
+		CountComparisons: true,
+		Filter:           BloomFilter(int64Hash, 4, 10),
+	})
+	for i := range int64(n) {
+		plain.Insert(i * 2)
+		filtered.Insert(i * 2)
+	}
+
+	misses := func(bwa *BWArr[int64]) uint64 {
+		before := bwa.Stats().Comparisons
+		for i := range int64(n) {
+			require.False(t, bwa.Has(i*2+1))
+		}
+		return bwa.Stats().Comparisons - before
+	}
+	plainCmps, filteredCmps := misses(plain), misses(filtered)
+	assert.Less(t, filteredCmps*5, plainCmps, "filtered %d, plain %d", filteredCmps, plainCmps)
+}
+
+func TestBloomFilter_Panics(t *testing.T) {
+	t.Parallel()
+	assert.Panics(t, func() { BloomFilter(int64Hash, 0, 0) })
+	assert.Panics(t, func() {
+		NewWithOptions(pageItemCmp, 0, Options{Filter: BloomFilter(int64Hash, 0, 10)}) //nolint:exhaustruct
+	})
+}
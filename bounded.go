@@ -0,0 +1,110 @@
+package bwarr
+
+// Eviction defines which element a Bounded evicts when it is full.
+type Eviction uint8
+
+const (
+	// EvictMin evicts the minimum element, so a Bounded keeps the greatest ones, as in top-K.
+	EvictMin Eviction = iota
+	// EvictMax evicts the maximum element, so a Bounded keeps the smallest ones.
+	EvictMax
+)
+
+// Bounded is a BWArr that holds at most a fixed number of elements. Inserting into a full Bounded evicts
+// the minimum or the maximum element, see Eviction. In addition to the BWArr methods, it has
+// Insert and ReplaceOrInsert that keep the bound. Other BWArr methods do not check it.
+type Bounded[T any] struct {
+	*BWArr[T]
+	// OnEvict, if set, is called with every evicted element, including the skipped ones. Optional.
+	OnEvict  func(evicted T)
+	capacity int
+	eviction Eviction
+}
+
+// NewBounded creates a new empty Bounded holding at most capacity elements.
+// Memory for capacity elements is allocated at once.
+func NewBounded[T any](cmp CmpFunc[T], capacity int, eviction Eviction) *Bounded[T] {
+	if capacity < 0 {
+		panic("bwarr: capacity must not be negative")
+	}
+	return &Bounded[T]{BWArr: New(cmp, capacity), OnEvict: nil, capacity: capacity, eviction: eviction}
+}
+
+// Capacity returns the maximum number of elements the Bounded holds.
+func (b *Bounded[T]) Capacity() int {
+	return b.capacity
+}
+
+// Insert adds an element, evicting the minimum or the maximum one if the Bounded is full.
+// If the element itself would be evicted right away, i.e. it is not greater than the minimum for EvictMin
+// or not less than the maximum for EvictMax, it is skipped without a merge and Insert returns false.
+// Equal elements are skipped too, keeping the older ones.
+func (b *Bounded[T]) Insert(element T) (inserted bool) {
+	if b.Len() < b.capacity {
+		b.BWArr.Insert(element)
+		return true
+	}
+	if b.capacity == 0 || !b.beats(element) {
+		b.evicted(element)
+		return false
+	}
+	evicted := b.evict()
+	b.BWArr.Insert(element) // After the deletion, so the BWArr never grows beyond capacity.
+	b.evicted(evicted)
+	return true
+}
+
+// ReplaceOrInsert replaces an existing equal element as BWArr.ReplaceOrInsert does,
+// otherwise it inserts the element and evicts the minimum or the maximum one if the Bounded overflows.
+// Unlike Insert, it merges the element even if it is evicted right away.
+func (b *Bounded[T]) ReplaceOrInsert(element T) (old T, found bool) {
+	if old, found = b.BWArr.ReplaceOrInsert(element); found || b.Len() <= b.capacity {
+		return old, found
+	}
+	b.evicted(b.evict())
+	return old, false
+}
+
+// evict deletes and returns the minimum or the maximum element, depending on the eviction policy.
+func (b *Bounded[T]) evict() T {
+	var evicted T
+	if b.eviction == EvictMin {
+		evicted, _ = b.DeleteMin()
+	} else {
+		evicted, _ = b.DeleteMax()
+	}
+	return evicted
+}
+
+// beats returns true if the element is not to be evicted instead of the element evicted from a full Bounded.
+func (b *Bounded[T]) beats(element T) bool {
+	if b.eviction == EvictMin {
+		minElem, _ := b.Min()
+		return b.cmp(element, minElem) > 0
+	}
+	maxElem, _ := b.Max()
+	return b.cmp(element, maxElem) < 0
+}
+
+func (b *Bounded[T]) evicted(element T) {
+	if b.OnEvict != nil {
+		b.OnEvict(element)
+	}
+}
+
+// TopK returns the k greatest elements of seq in descending order. Of equal elements, the earliest ones
+// are preferred. seq is consumed as a stream, so only k elements are kept in memory at once; an iter.Seq
+// can be passed as seq.
+func TopK[T any](cmp CmpFunc[T], k int, seq func(yield func(T) bool)) []T {
+	top := NewBounded(cmp, k, EvictMin)
+	seq(func(v T) bool {
+		top.Insert(v)
+		return true
+	})
+	res := make([]T, 0, top.Len())
+	top.Descend(func(v T) bool {
+		res = append(res, v)
+		return true
+	})
+	return res
+}
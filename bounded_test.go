@@ -0,0 +1,117 @@
+package bwarr
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBounded_Insert(t *testing.T) {
+	t.Parallel()
+	for _, eviction := range []Eviction{EvictMin, EvictMax} {
+		r := rand.New(rand.NewSource(29)) //nolint:gosec
+		const capacity = 37
+		b := NewBounded(int64Cmp, capacity, eviction)
+		var evicted, inserted []int64
+		b.OnEvict = func(v int64) { evicted = append(evicted, v) }
+		for range 1000 {
+			v := r.Int63n(500)
+			inserted = append(inserted, v)
+			b.Insert(v)
+			require.LessOrEqual(t, b.Len(), capacity)
+		}
+		require.NoError(t, b.Validate())
+
+		slices.Sort(inserted)
+		want := inserted[len(inserted)-capacity:]
+		if eviction == EvictMax {
+			want = inserted[:capacity]
+		}
+		var got []int64
+		b.Ascend(func(v int64) bool {
+			got = append(got, v)
+			return true
+		})
+		assert.Equal(t, want, got, "eviction %d", eviction)
+
+		all := append(slices.Clone(got), evicted...)
+		slices.Sort(all)
+		assert.Equal(t, inserted, all, "every element is either kept or evicted")
+	}
+}
+
+func TestBounded_InsertSkipsWithoutMerge(t *testing.T) {
+	t.Parallel()
+	b := NewBounded(int64Cmp, 4, EvictMin)
+	for i := range int64(4) {
+		assert.True(t, b.Insert(i+10))
+	}
+	merges := b.Stats().Merges
+	var evicted []int64
+	b.OnEvict = func(v int64) { evicted = append(evicted, v) }
+
+	assert.False(t, b.Insert(5))
+	assert.False(t, b.Insert(10)) // Equal to the minimum, the older one is kept.
+	assert.Equal(t, merges, b.Stats().Merges)
+	assert.True(t, b.Insert(20))
+	assert.Equal(t, []int64{5, 10, 10}, evicted)
+
+	got, found := b.Min()
+	assert.True(t, found)
+	assert.Equal(t, int64(11), got)
+	assert.Equal(t, 4, b.Len())
+	assert.Equal(t, 4, b.Capacity())
+}
+
+func TestBounded_ReplaceOrInsert(t *testing.T) {
+	t.Parallel()
+	b := NewBounded(pageItemCmp, 2, EvictMax)
+	b.Insert(pageItem{key: 1, seq: 0})
+	b.Insert(pageItem{key: 2, seq: 1})
+
+	old, found := b.ReplaceOrInsert(pageItem{key: 2, seq: 2})
+	assert.True(t, found)
+	assert.Equal(t, pageItem{key: 2, seq: 1}, old)
+
+	_, found = b.ReplaceOrInsert(pageItem{key: 0, seq: 3})
+	assert.False(t, found)
+	assert.Equal(t, 2, b.Len())
+	assert.False(t, b.Has(pageItem{key: 2})) //nolint:exhaustruct
+
+	var evicted []pageItem
+	b.OnEvict = func(e pageItem) { evicted = append(evicted, e) }
+	_, found = b.ReplaceOrInsert(pageItem{key: 5, seq: 4})
+	assert.False(t, found)
+	assert.Equal(t, []pageItem{{key: 5, seq: 4}}, evicted)
+	assert.Equal(t, 2, b.Len())
+	assert.False(t, b.Has(pageItem{key: 5})) //nolint:exhaustruct
+}
+
+func TestBounded_ZeroCapacity(t *testing.T) {
+	t.Parallel()
+	b := NewBounded(int64Cmp, 0, EvictMin)
+	var evicted int
+	b.OnEvict = func(int64) { evicted++ }
+	assert.False(t, b.Insert(1))
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, 1, evicted)
+	assert.Panics(t, func() { NewBounded(int64Cmp, -1, EvictMin) })
+}
+
+func TestTopK(t *testing.T) {
+	t.Parallel()
+	values := []int64{5, 1, 9, 3, 9, 7, 2}
+	seq := func(yield func(int64) bool) {
+		for _, v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	assert.Equal(t, []int64{9, 9, 7}, TopK(int64Cmp, 3, seq))
+	assert.Equal(t, []int64{9, 9, 7, 5, 3, 2, 1}, TopK(int64Cmp, 10, seq))
+	assert.Empty(t, TopK(int64Cmp, 0, seq))
+}
@@ -0,0 +1,93 @@
+package bwarr
+
+type boundKind uint8
+
+const (
+	boundUnbounded boundKind = iota
+	boundIncluded
+	boundExcluded
+)
+
+// Bound is a lower or upper bound of a range of elements, see AscendBounds.
+// The zero value is Unbounded.
+type Bound[T any] struct {
+	kind  boundKind
+	value T
+}
+
+// Included returns a bound that includes elements equal to value.
+func Included[T any](value T) Bound[T] {
+	return Bound[T]{kind: boundIncluded, value: value}
+}
+
+// Excluded returns a bound that excludes elements equal to value.
+func Excluded[T any](value T) Bound[T] {
+	return Bound[T]{kind: boundExcluded, value: value}
+}
+
+// Unbounded returns a bound that does not limit the range.
+func Unbounded[T any]() Bound[T] {
+	return Bound[T]{} //nolint:exhaustruct
+}
+
+// Value returns the value of the bound and true, or the zero value of T and false if the bound is Unbounded.
+func (b Bound[T]) Value() (T, bool) {
+	return b.value, b.kind != boundUnbounded
+}
+
+// IsIncluded returns true if the bound includes elements equal to its value.
+func (b Bound[T]) IsIncluded() bool {
+	return b.kind == boundIncluded
+}
+
+// AscendBounds calls the iterator function for each element in the BWArr between lo and hi,
+// in ascending order. Iteration stops early if the iterator returns false.
+// For example, AscendBounds(Excluded(a), Included(b), fn) visits elements in (a, b].
+// The operation has O(N*Log(N)) time complexity in the worst case.
+func (bwa *BWArr[T]) AscendBounds(lo, hi Bound[T], iterator IteratorFunc[T]) {
+	iter := createAscIteratorBounds(bwa, lo, hi)
+	for val, ok := iter.next(); ok; val, ok = iter.next() {
+		if !iterator(*val) {
+			break
+		}
+	}
+}
+
+// DescendBounds calls the iterator function for each element in the BWArr between lo and hi,
+// in descending order. Iteration stops early if the iterator returns false.
+// The operation has O(N*Log(N)) time complexity in the worst case.
+func (bwa *BWArr[T]) DescendBounds(lo, hi Bound[T], iterator IteratorFunc[T]) {
+	iter := createDescIteratorBounds(bwa, lo, hi)
+	for val, ok := iter.prev(); ok; val, ok = iter.prev() {
+		if !iterator(*val) {
+			break
+		}
+	}
+}
+
+// AscendGreaterThan calls the iterator function for each element in the BWArr that is
+// greater than the given element, in ascending order. It is AscendBounds(Excluded(elem), Unbounded()).
+func (bwa *BWArr[T]) AscendGreaterThan(elem T, iterator IteratorFunc[T]) {
+	bwa.AscendBounds(Excluded(elem), Unbounded[T](), iterator)
+}
+
+// DescendLessOrEqual calls the iterator function for each element in the BWArr that is
+// less than or equal to the given element, in descending order. It is DescendBounds(Unbounded(), Included(elem)).
+func (bwa *BWArr[T]) DescendLessOrEqual(elem T, iterator IteratorFunc[T]) {
+	bwa.DescendBounds(Unbounded[T](), Included(elem), iterator)
+}
+
+// CountBounds returns the number of elements in the BWArr between lo and hi.
+// It does not visit the elements, so it has O(Log^2(N)) time complexity.
+func (bwa *BWArr[T]) CountBounds(lo, hi Bound[T]) int {
+	n := 0
+	for i := range bwa.whiteSegments {
+		if bwa.total&(1<<i) == 0 {
+			continue
+		}
+		if first, last, ok := bwa.segmentBounds(&bwa.whiteSegments[i], lo, hi); ok {
+			n += bwa.whiteSegments[i].countNonDeleted(first, last)
+		}
+	}
+	return n
+}
@@ -0,0 +1,102 @@
+package bwarr
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inBounds reports whether v is between lo and hi, it is the reference for AscendBounds.
+func inBounds(v int64, lo, hi Bound[int64]) bool {
+	if l, ok := lo.Value(); ok && (v < l || (v == l && !lo.IsIncluded())) {
+		return false
+	}
+	if h, ok := hi.Value(); ok && (v > h || (v == h && !hi.IsIncluded())) {
+		return false
+	}
+	return true
+}
+
+func TestBWArr_Bounds(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(3)) //nolint:gosec
+	for name, bwa := range map[string]*BWArr[int64]{"generic": New(int64Cmp, 0), "ordered": NewOrdered[int64](0)} {
+		var model []int64
+		for range 300 {
+			v := r.Int63n(100)
+			bwa.Insert(v)
+			model = append(model, v)
+		}
+		for range 100 {
+			v := r.Int63n(100)
+			if _, found := bwa.Delete(v); found {
+				model = slices.Delete(model, slices.Index(model, v), slices.Index(model, v)+1)
+			}
+		}
+		slices.Sort(model)
+
+		bounds := func(v int64) []Bound[int64] {
+			return []Bound[int64]{Unbounded[int64](), Included(v), Excluded(v)}
+		}
+		for range 50 {
+			a, b := r.Int63n(110)-5, r.Int63n(110)-5
+			for _, lo := range bounds(a) {
+				for _, hi := range bounds(b) {
+					var want []int64
+					for _, v := range model {
+						if inBounds(v, lo, hi) {
+							want = append(want, v)
+						}
+					}
+					var got []int64
+					bwa.AscendBounds(lo, hi, func(v int64) bool { got = append(got, v); return true })
+					require.Equal(t, want, got, "%s: asc %+v %+v", name, lo, hi)
+					require.Equal(t, len(want), bwa.CountBounds(lo, hi), "%s: count %+v %+v", name, lo, hi)
+
+					got = got[:0]
+					bwa.DescendBounds(lo, hi, func(v int64) bool { got = append(got, v); return true })
+					slices.Reverse(want)
+					require.Equal(t, want, got, "%s: desc %+v %+v", name, lo, hi)
+				}
+			}
+		}
+	}
+}
+
+func TestBWArr_AscendGreaterThanAndDescendLessOrEqual(t *testing.T) {
+	t.Parallel()
+	bwa := NewFromSlice(int64Cmp, []int64{5, 1, 3, 3, 7, 9, 5})
+
+	var got []int64
+	bwa.AscendGreaterThan(3, func(v int64) bool { got = append(got, v); return true })
+	assert.Equal(t, []int64{5, 5, 7, 9}, got)
+
+	got = got[:0]
+	bwa.DescendLessOrEqual(5, func(v int64) bool { got = append(got, v); return true })
+	assert.Equal(t, []int64{5, 5, 3, 3, 1}, got)
+
+	got = got[:0]
+	bwa.DescendLessOrEqual(5, func(v int64) bool { got = append(got, v); return v > 3 })
+	assert.Equal(t, []int64{5, 5, 3}, got)
+
+	got = got[:0]
+	bwa.AscendBounds(Included[int64](9), Excluded[int64](1), func(v int64) bool { got = append(got, v); return true })
+	assert.Empty(t, got)
+}
+
+func TestBound(t *testing.T) {
+	t.Parallel()
+	var zero Bound[int]
+	_, ok := zero.Value()
+	assert.False(t, ok)
+	assert.Equal(t, Unbounded[int](), zero)
+
+	v, ok := Included(3).Value()
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+	assert.True(t, Included(3).IsIncluded())
+	assert.False(t, Excluded(3).IsIncluded())
+}
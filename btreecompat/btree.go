@@ -0,0 +1,186 @@
+// Package btreecompat provides BTreeG, a drop-in replacement for the generic BTreeG of
+// github.com/google/btree backed by bwarr.BWArr. To migrate, change the import:
+//
+//	import btree "github.com/dronnix/bwarr/btreecompat"
+//
+// BTreeG stores unique items: inserting an item equal to an existing one replaces it, as in btree.
+// The degree is validated for compatibility but does not affect the layout. Free lists are
+// accepted and ignored, BWArr reuses its memory on its own. Unlike btree, Clone copies all items
+// in O(N) time instead of sharing them copy-on-write.
+package btreecompat
+
+import (
+	"cmp"
+
+	"github.com/dronnix/bwarr"
+)
+
+// LessFunc determines how to order a type 'T'. It should implement a strict ordering, and should
+// return true if within that ordering, 'a' < 'b'.
+type LessFunc[T any] func(a, b T) bool
+
+// ItemIteratorG allows callers of Ascend* and Descend* to iterate in-order over portions of
+// the tree. When this function returns false, iteration will stop and the associated Ascend*
+// or Descend* function will immediately return.
+type ItemIteratorG[T any] func(item T) bool
+
+// Ordered represents the set of types for which the '<' operator work.
+type Ordered = cmp.Ordered
+
+// Less returns a default LessFunc that uses the '<' operator for types that support it.
+func Less[T Ordered]() LessFunc[T] {
+	return func(a, b T) bool { return a < b }
+}
+
+// FreeListG is accepted for compatibility with btree, it does not hold anything.
+type FreeListG[T any] struct{}
+
+// NewFreeListG creates a new free list. The size is ignored.
+func NewFreeListG[T any](_ int) *FreeListG[T] {
+	return &FreeListG[T]{}
+}
+
+// BTreeG is an ordered set of items with the API of btree.BTreeG.
+// Write operations are not safe for concurrent mutation by multiple goroutines,
+// but Read operations are.
+type BTreeG[T any] struct {
+	bwa *bwarr.BWArr[T]
+}
+
+// NewG creates a new BTreeG ordered by less. It panics if degree <= 1, as btree.NewG does.
+func NewG[T any](degree int, less LessFunc[T]) *BTreeG[T] {
+	return NewWithFreeListG(degree, less, nil)
+}
+
+// NewOrderedG creates a new BTreeG for ordered types.
+func NewOrderedG[T Ordered](degree int) *BTreeG[T] {
+	return NewG[T](degree, Less[T]())
+}
+
+// NewWithFreeListG creates a new BTreeG. The free list is ignored.
+func NewWithFreeListG[T any](degree int, less LessFunc[T], _ *FreeListG[T]) *BTreeG[T] {
+	if degree <= 1 {
+		panic("bad degree")
+	}
+	cmpFunc := func(a, b T) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		default:
+			return 0
+		}
+	}
+	return &BTreeG[T]{bwa: bwarr.New(cmpFunc, 0)}
+}
+
+// Clone returns an independent copy of the tree. Unlike btree, it copies all items in O(N) time.
+func (t *BTreeG[T]) Clone() *BTreeG[T] {
+	return &BTreeG[T]{bwa: t.bwa.Clone()}
+}
+
+// ReplaceOrInsert adds the given item to the tree. If an item in the tree already equals
+// the given one, it is removed from the tree and returned, and the second return value is true.
+// Otherwise, (zeroValue, false) is returned.
+func (t *BTreeG[T]) ReplaceOrInsert(item T) (_ T, _ bool) {
+	return t.bwa.ReplaceOrInsert(item)
+}
+
+// Delete removes an item equal to the passed in item from the tree, returning it.
+// If no such item exists, returns (zeroValue, false).
+func (t *BTreeG[T]) Delete(item T) (T, bool) {
+	return t.bwa.Delete(item)
+}
+
+// DeleteMin removes the smallest item in the tree and returns it.
+// If no such item exists, returns (zeroValue, false).
+func (t *BTreeG[T]) DeleteMin() (T, bool) {
+	return t.bwa.DeleteMin()
+}
+
+// DeleteMax removes the largest item in the tree and returns it.
+// If no such item exists, returns (zeroValue, false).
+func (t *BTreeG[T]) DeleteMax() (T, bool) {
+	return t.bwa.DeleteMax()
+}
+
+// Get looks for the key item in the tree, returning it. It returns (zeroValue, false)
+// if unable to find that item.
+func (t *BTreeG[T]) Get(key T) (_ T, _ bool) {
+	return t.bwa.Get(key)
+}
+
+// Has returns true if the given key is in the tree.
+func (t *BTreeG[T]) Has(key T) bool {
+	return t.bwa.Has(key)
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the tree is empty.
+func (t *BTreeG[T]) Min() (_ T, _ bool) {
+	return t.bwa.Min()
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the tree is empty.
+func (t *BTreeG[T]) Max() (_ T, _ bool) {
+	return t.bwa.Max()
+}
+
+// Len returns the number of items currently in the tree.
+func (t *BTreeG[T]) Len() int {
+	return t.bwa.Len()
+}
+
+// Clear removes all items from the tree. If addNodesToFreelist is true, the memory is kept
+// for reuse, otherwise it is released.
+func (t *BTreeG[T]) Clear(addNodesToFreelist bool) {
+	t.bwa.Clear(!addNodesToFreelist)
+}
+
+// Ascend calls the iterator for every value in the tree within the range [first, last],
+// until iterator returns false.
+func (t *BTreeG[T]) Ascend(iterator ItemIteratorG[T]) {
+	t.bwa.Ascend(bwarr.IteratorFunc[T](iterator))
+}
+
+// AscendRange calls the iterator for every value in the tree within the range
+// [greaterOrEqual, lessThan), until iterator returns false.
+func (t *BTreeG[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	t.bwa.AscendRange(greaterOrEqual, lessThan, bwarr.IteratorFunc[T](iterator))
+}
+
+// AscendLessThan calls the iterator for every value in the tree within the range
+// [first, pivot), until iterator returns false.
+func (t *BTreeG[T]) AscendLessThan(pivot T, iterator ItemIteratorG[T]) {
+	t.bwa.AscendLessThan(pivot, bwarr.IteratorFunc[T](iterator))
+}
+
+// AscendGreaterOrEqual calls the iterator for every value in the tree within
+// the range [pivot, last], until iterator returns false.
+func (t *BTreeG[T]) AscendGreaterOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	t.bwa.AscendGreaterOrEqual(pivot, bwarr.IteratorFunc[T](iterator))
+}
+
+// Descend calls the iterator for every value in the tree within the range [last, first],
+// until iterator returns false.
+func (t *BTreeG[T]) Descend(iterator ItemIteratorG[T]) {
+	t.bwa.Descend(bwarr.IteratorFunc[T](iterator))
+}
+
+// DescendRange calls the iterator for every value in the tree within the range
+// [lessOrEqual, greaterThan), until iterator returns false.
+func (t *BTreeG[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIteratorG[T]) {
+	t.bwa.DescendBounds(bwarr.Excluded(greaterThan), bwarr.Included(lessOrEqual), bwarr.IteratorFunc[T](iterator))
+}
+
+// DescendLessOrEqual calls the iterator for every value in the tree within the range
+// [pivot, first], until iterator returns false.
+func (t *BTreeG[T]) DescendLessOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	t.bwa.DescendLessOrEqual(pivot, bwarr.IteratorFunc[T](iterator))
+}
+
+// DescendGreaterThan calls the iterator for every value in the tree within
+// the range [last, pivot), until iterator returns false.
+func (t *BTreeG[T]) DescendGreaterThan(pivot T, iterator ItemIteratorG[T]) {
+	t.bwa.DescendBounds(bwarr.Excluded(pivot), bwarr.Unbounded[T](), bwarr.IteratorFunc[T](iterator))
+}
@@ -0,0 +1,321 @@
+package btreecompat
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Conformance tests follow the behavior of github.com/google/btree's generic tests.
+
+const treeSize = 10000
+
+func perm(n int) []int {
+	return rand.Perm(n)
+}
+
+func rang(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+func rangRev(n int) []int {
+	out := rang(n)
+	slices.Reverse(out)
+	return out
+}
+
+func all(t *BTreeG[int]) []int {
+	var out []int
+	t.Ascend(func(a int) bool {
+		out = append(out, a)
+		return true
+	})
+	return out
+}
+
+func allRev(t *BTreeG[int]) []int {
+	var out []int
+	t.Descend(func(a int) bool {
+		out = append(out, a)
+		return true
+	})
+	return out
+}
+
+func collect(out *[]int, stopAt int) ItemIteratorG[int] {
+	return func(a int) bool {
+		if a == stopAt {
+			return false
+		}
+		*out = append(*out, a)
+		return true
+	}
+}
+
+func TestBTreeG(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](32)
+	const iterations = 10
+	for range iterations {
+		_, found := tr.Min()
+		require.False(t, found)
+		_, found = tr.Max()
+		require.False(t, found)
+		for _, item := range perm(treeSize) {
+			_, found := tr.ReplaceOrInsert(item)
+			require.False(t, found, "insert found item %d", item)
+		}
+		for _, item := range perm(treeSize) {
+			require.True(t, tr.Has(item))
+			got, found := tr.Get(item)
+			require.True(t, found)
+			require.Equal(t, item, got)
+		}
+		for _, item := range perm(treeSize) {
+			old, found := tr.ReplaceOrInsert(item)
+			require.True(t, found, "insert didn't find item %d", item)
+			require.Equal(t, item, old)
+		}
+		minItem, _ := tr.Min()
+		maxItem, _ := tr.Max()
+		require.Equal(t, 0, minItem)
+		require.Equal(t, treeSize-1, maxItem)
+		require.Equal(t, rang(treeSize), all(tr))
+		require.Equal(t, rangRev(treeSize), allRev(tr))
+		for _, item := range perm(treeSize) {
+			_, found := tr.Delete(item)
+			require.True(t, found, "didn't find %d", item)
+		}
+		require.Empty(t, all(tr))
+		require.Equal(t, 0, tr.Len())
+	}
+}
+
+func TestBTreeG_ReplaceKeepsOneItem(t *testing.T) {
+	t.Parallel()
+	type kv struct {
+		k int
+		v string
+	}
+	tr := NewG[kv](2, func(a, b kv) bool { return a.k < b.k })
+	tr.ReplaceOrInsert(kv{1, "a"})
+	old, found := tr.ReplaceOrInsert(kv{1, "b"})
+	assert.True(t, found)
+	assert.Equal(t, kv{1, "a"}, old)
+	assert.Equal(t, 1, tr.Len())
+	got, _ := tr.Get(kv{1, ""})
+	assert.Equal(t, kv{1, "b"}, got)
+}
+
+func TestDeleteMin(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](3)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	for v, found := tr.DeleteMin(); found; v, found = tr.DeleteMin() {
+		got = append(got, v)
+	}
+	assert.Equal(t, rang(100), got)
+}
+
+func TestDeleteMax(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](3)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	for v, found := tr.DeleteMax(); found; v, found = tr.DeleteMax() {
+		got = append(got, v)
+	}
+	assert.Equal(t, rangRev(100), got)
+}
+
+func TestEmptyTree(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, f := range []func() (int, bool){tr.DeleteMin, tr.DeleteMax, tr.Min, tr.Max} {
+		v, found := f()
+		assert.False(t, found)
+		assert.Zero(t, v)
+	}
+	v, found := tr.Delete(1)
+	assert.False(t, found)
+	assert.Zero(t, v)
+	v, found = tr.Get(1)
+	assert.False(t, found)
+	assert.Zero(t, v)
+}
+
+func TestAscendRange(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	tr.AscendRange(40, 60, collect(&got, -1))
+	assert.Equal(t, rang(100)[40:60], got)
+	got = got[:0]
+	tr.AscendRange(40, 60, collect(&got, 51))
+	assert.Equal(t, rang(100)[40:51], got)
+}
+
+func TestDescendRange(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	tr.DescendRange(60, 40, collect(&got, -1))
+	assert.Equal(t, rangRev(100)[39:59], got)
+	got = got[:0]
+	tr.DescendRange(60, 40, collect(&got, 50))
+	assert.Equal(t, rangRev(100)[39:49], got)
+	got = got[:0]
+	tr.DescendRange(40, 60, collect(&got, -1))
+	assert.Empty(t, got)
+	tr.DescendRange(40, 40, collect(&got, -1))
+	assert.Empty(t, got)
+}
+
+func TestAscendLessThan(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	tr.AscendLessThan(60, collect(&got, -1))
+	assert.Equal(t, rang(100)[:60], got)
+	got = got[:0]
+	tr.AscendLessThan(60, collect(&got, 51))
+	assert.Equal(t, rang(100)[:51], got)
+}
+
+func TestDescendLessOrEqual(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	tr.DescendLessOrEqual(40, collect(&got, -1))
+	assert.Equal(t, rangRev(100)[59:], got)
+	got = got[:0]
+	tr.DescendLessOrEqual(60, collect(&got, 50))
+	assert.Equal(t, rangRev(100)[39:49], got)
+	got = got[:0]
+	tr.DescendLessOrEqual(40, collect(&got, 40))
+	assert.Empty(t, got)
+}
+
+func TestAscendGreaterOrEqual(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	tr.AscendGreaterOrEqual(40, collect(&got, -1))
+	assert.Equal(t, rang(100)[40:], got)
+	got = got[:0]
+	tr.AscendGreaterOrEqual(40, collect(&got, 51))
+	assert.Equal(t, rang(100)[40:51], got)
+}
+
+func TestDescendGreaterThan(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	var got []int
+	tr.DescendGreaterThan(40, collect(&got, -1))
+	assert.Equal(t, rangRev(100)[:59], got)
+	got = got[:0]
+	tr.DescendGreaterThan(40, collect(&got, 50))
+	assert.Equal(t, rangRev(100)[:49], got)
+	got = got[:0]
+	tr.DescendGreaterThan(99, collect(&got, -1))
+	assert.Empty(t, got)
+}
+
+func TestCloneConcurrentOperations(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	clone := tr.Clone()
+	for v := range 500 {
+		tr.Delete(v)
+	}
+	clone.ReplaceOrInsert(1000)
+	assert.Equal(t, rang(1000)[500:], all(tr))
+	assert.Equal(t, rang(1001), all(clone))
+}
+
+func TestConcurrentReads(t *testing.T) {
+	t.Parallel()
+	tr := NewOrderedG[int](2)
+	for _, v := range perm(treeSize) {
+		tr.ReplaceOrInsert(v)
+	}
+	for _, v := range perm(treeSize)[:treeSize/3] { // Lazy deletions move the bounds of the segments.
+		tr.Delete(v)
+	}
+	want := all(tr)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				minItem, _ := tr.Min()
+				maxItem, _ := tr.Max()
+				assert.Equal(t, want[0], minItem)
+				assert.Equal(t, want[len(want)-1], maxItem)
+				assert.Equal(t, slices.Contains(want, i), tr.Has(i))
+				tr.AscendGreaterOrEqual(i, func(int) bool { return false })
+				tr.DescendLessOrEqual(treeSize-i, func(int) bool { return false })
+			}
+			assert.Equal(t, want, all(tr))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClear(t *testing.T) {
+	t.Parallel()
+	for _, addNodesToFreelist := range []bool{false, true} {
+		tr := NewWithFreeListG[int](2, Less[int](), NewFreeListG[int](32))
+		for _, v := range perm(100) {
+			tr.ReplaceOrInsert(v)
+		}
+		tr.Clear(addNodesToFreelist)
+		assert.Equal(t, 0, tr.Len())
+		assert.Empty(t, all(tr))
+		tr.ReplaceOrInsert(5)
+		assert.Equal(t, []int{5}, all(tr))
+	}
+}
+
+func TestBadDegree(t *testing.T) {
+	t.Parallel()
+	assert.Panics(t, func() { NewOrderedG[int](1) })
+	assert.Panics(t, func() { NewG[int](0, Less[int]()) })
+	assert.NotPanics(t, func() { NewOrderedG[string](2) })
+}
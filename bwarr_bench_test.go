@@ -3,6 +3,7 @@ package bwarr
 // Benchmarks with prefix QA (quality assurance) is for tracking performance regressions.
 
 import (
+	"cmp"
 	"math/rand"
 	"testing"
 )
@@ -51,6 +52,49 @@ func BenchmarkQA_HasFound(b *testing.B) {
 	}
 }
 
+func BenchmarkQA_OrderedInsert(b *testing.B) {
+	bwa := NewOrdered[int64](elemsOnStart)
+
+	for range elemsOnStart {
+		bwa.Insert(rand.Int63())
+	}
+	preparedData := make([]int64, b.N)
+	for i := range b.N {
+		preparedData[i] = rand.Int63()
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := range b.N {
+		bwa.Insert(preparedData[i])
+	}
+}
+
+// Fits in L2 cache, so comparisons rather than memory accesses dominate the cost of searches.
+const elemsCacheResident = 64*1024 - 1
+
+func BenchmarkQA_CompareHasFound(b *testing.B) {
+	benchmarkHasFound(b, New(cmp.Compare[int64], elemsCacheResident))
+}
+
+func BenchmarkQA_OrderedHasFound(b *testing.B) {
+	benchmarkHasFound(b, NewOrdered[int64](elemsCacheResident))
+}
+
+func benchmarkHasFound(b *testing.B, bwa *BWArr[int64]) {
+	b.Helper()
+	preparedData := make([]int64, elemsCacheResident)
+	for i := range preparedData {
+		preparedData[i] = rand.Int63()
+		bwa.Insert(preparedData[i])
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := range b.N {
+		bwa.Has(preparedData[i%elemsCacheResident])
+	}
+}
+
 func BenchmarkQA_HasNotFoundWorst(b *testing.B) {
 	bwa := New(int64Cmp, elemsOnStart)
 
@@ -69,6 +113,74 @@ func BenchmarkQA_HasNotFoundWorst(b *testing.B) {
 	}
 }
 
+func BenchmarkQA_CascadingHasFound(b *testing.B) { //nolint:dupl
+	bwa := NewWithOptions(int64Cmp, elemsOnStart, Options{FractionalCascading: true}) //nolint:exhaustruct
+	preparedData := make([]int64, elemsOnStart)
+
+	for i := range elemsOnStart {
+		preparedData[i] = rand.Int63()
+		bwa.Insert(preparedData[i])
+	}
+	b.SetBytes(8) //nolint:exhaustruct
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := range b.N {
+		bwa.Has(preparedData[i%elemsOnStart])
+	}
+}
+
+func BenchmarkQA_CascadingHasNotFoundWorst(b *testing.B) { //nolint:dupl
+	bwa := NewWithOptions(int64Cmp, elemsOnStart, Options{FractionalCascading: true}) //nolint:exhaustruct
+
+	for range elemsOnStart {
+		bwa.Insert(rand.Int63())
+	}
+	preparedData := make([]int64, b.N)
+	for i := range b.N {
+		preparedData[i] = rand.Int63()
+	}
+	b.SetBytes(8) //nolint:exhaustruct
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := range b.N {
+		bwa.Has(preparedData[i])
+	}
+}
+
+func BenchmarkQA_IndexedHasFound(b *testing.B) { //nolint:dupl
+	bwa := NewWithOptions(int64Cmp, elemsOnStart, Options{SearchIndexMinRank: 16}) //nolint:exhaustruct
+	preparedData := make([]int64, elemsOnStart)
+
+	for i := range elemsOnStart {
+		preparedData[i] = rand.Int63()
+		bwa.Insert(preparedData[i])
+	}
+	b.SetBytes(8) //nolint:exhaustruct
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := range b.N {
+		bwa.Has(preparedData[i%elemsOnStart])
+	}
+}
+
+func BenchmarkQA_IndexedHasNotFoundWorst(b *testing.B) { //nolint:dupl
+	bwa := NewWithOptions(int64Cmp, elemsOnStart, Options{SearchIndexMinRank: 16}) //nolint:exhaustruct
+
+	for range elemsOnStart {
+		bwa.Insert(rand.Int63())
+	}
+	preparedData := make([]int64, b.N)
+	for i := range b.N {
+		preparedData[i] = rand.Int63()
+	}
+	b.SetBytes(8) //nolint:exhaustruct
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := range b.N {
+		bwa.Has(preparedData[i])
+	}
+}
+
 func BenchmarkQA_Min(b *testing.B) {
 	bwa := New(int64Cmp, elemsOnStart)
 
@@ -609,6 +609,32 @@ func TestBWArr_RandomDelete(t *testing.T) {
 	}
 }
 
+func TestBWArr_InsertAfterDelete(t *testing.T) {
+	t.Parallel()
+	bwa := New(int64Cmp, 0)
+	for i := range 18 { // Segments of ranks 1 and 4 are active.
+		bwa.Insert(int64(i))
+	}
+	_, found := bwa.Delete(16) // Demotes segment of rank 1, it keeps a deleted element.
+	require.True(t, found)
+	bwa.Insert(100) // Segment of rank 1 becomes active again.
+	validateBWArr(t, bwa)
+	require.Equal(t, 18, bwa.Len())
+	for _, e := range []int64{0, 15, 17, 100} {
+		require.True(t, bwa.Has(e), "element %d should be found", e)
+	}
+
+	r := rand.New(rand.NewSource(42))
+	for range 2000 {
+		if r.Intn(3) == 0 {
+			bwa.Delete(int64(r.Intn(100)))
+		} else {
+			bwa.Insert(int64(r.Intn(100)))
+		}
+		validateBWArr(t, bwa)
+	}
+}
+
 func TestBWArr_Len(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -904,6 +930,21 @@ func TestBWArr_AscendRangeOutOfBounds(t *testing.T) {
 	bwa.AscendRange(from, to, iter)
 }
 
+func TestBWArr_RangeInverted(t *testing.T) {
+	t.Parallel()
+	bwa := New(int64Cmp, 0)
+	bwa.Insert(3)
+	bwa.Insert(30)
+	iter := func(_ int64) bool {
+		t.Fail()
+		return true
+	}
+	bwa.AscendRange(9, 7, iter)
+	bwa.DescendRange(9, 7, iter)
+	bwa.AscendRange(30, 3, iter)
+	bwa.DescendRange(30, 3, iter)
+}
+
 func TestBWArr_Descend(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -1118,6 +1159,67 @@ func TestBWArr_DescIteratorsShouldStop(t *testing.T) {
 	bwa.DescendRange(7, elemsNum, iter)
 }
 
+func TestBWArr_Rebuild(t *testing.T) {
+	t.Parallel()
+
+	bwa := New(stabValCmp, 0)
+	bwa.Rebuild()
+	assert.Equal(t, 0, bwa.Len())
+
+	const elemsNum = 1000
+	r := rand.New(rand.NewSource(42))
+	seq := 0
+	for range elemsNum {
+		bwa.Insert(stabVal{val: r.Intn(50), seq: seq})
+		seq++
+	}
+	for range elemsNum / 3 {
+		bwa.Delete(stabVal{val: r.Intn(50)})
+	}
+	require.Greater(t, bwa.total, bwa.Len(), "there should be lazy-deleted elements")
+	n := bwa.Len()
+	bwa.Rebuild()
+	assert.Equal(t, n, bwa.total)
+	for i := range bwa.whiteSegments {
+		assert.Equal(t, 0, bwa.whiteSegments[i].deletedNum)
+		if n&(1<<i) == 0 {
+			assert.Empty(t, bwa.whiteSegments[i].elements, "inactive segment %d should be released", i)
+		}
+	}
+	validateBWArr(t, bwa)
+	assert.False(t, bwa.CompactIfNeeded(0))
+
+	// Equal elements must still be deleted in the insertion order.
+	expected := make([]stabVal, 0, n)
+	for range n {
+		minElem, found := bwa.DeleteMin()
+		require.True(t, found)
+		expected = append(expected, minElem)
+	}
+	for i := 1; i < len(expected); i++ {
+		if expected[i-1].val == expected[i].val {
+			require.Less(t, expected[i-1].seq, expected[i].seq)
+		}
+	}
+}
+
+func TestBWArr_CompactIfNeeded(t *testing.T) {
+	t.Parallel()
+
+	bwa := New(int64Cmp, 0)
+	assert.False(t, bwa.CompactIfNeeded(0))
+	for i := range int64(64) {
+		bwa.Insert(i)
+	}
+	for i := range int64(10) {
+		bwa.Delete(i * 5)
+	}
+	assert.False(t, bwa.CompactIfNeeded(0.5))
+	assert.True(t, bwa.CompactIfNeeded(0.1))
+	assert.Equal(t, 54, bwa.total)
+	validateBWArr(t, bwa)
+}
+
 func TestBWArr_Compact(t *testing.T) {
 	t.Parallel()
 
@@ -1282,6 +1384,79 @@ func TestBWArr_DeleteAutoCompact(t *testing.T) {
 	assert.Empty(t, testArray.whiteSegments[1].deleted)
 }
 
+func TestBWArr_ShrinkPolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		policy        ShrinkPolicy
+		keptAllocated []int // Ranks of allocated segments after deleting down to 4 elements.
+	}{
+		{name: "eager", policy: ShrinkEager, keptAllocated: []int{0, 1, 2}},
+		{name: "never", policy: ShrinkNever, keptAllocated: []int{0, 1, 2, 3, 4}},
+		{name: "hysteresis", policy: ShrinkHysteresis, keptAllocated: []int{0, 1, 2, 3}},
+	}
+	for _, tt := range tests { //nolint:paralleltest
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			bwa := NewWithOptions(int64Cmp, 0, Options{ShrinkPolicy: tt.policy}) //nolint:exhaustruct
+			for i := range int64(16) {
+				bwa.Insert(i)
+			}
+			for i := range int64(12) {
+				bwa.Delete(i)
+				validateBWArr(t, bwa)
+			}
+			require.Equal(t, 4, bwa.Len())
+			var allocated []int
+			for rank := range bwa.whiteSegments {
+				if len(bwa.whiteSegments[rank].elements) > 0 {
+					allocated = append(allocated, rank)
+				}
+			}
+			assert.Equal(t, tt.keptAllocated, allocated)
+		})
+	}
+}
+
+func TestBWArr_GrowAndShrinkToFit(t *testing.T) {
+	t.Parallel()
+	bwa := New(int64Cmp, 0)
+	bwa.Insert(42)
+	bwa.Grow(0)
+	assert.Len(t, bwa.whiteSegments, 1)
+	bwa.Grow(100) // 101 elements in total need segments up to rank 6.
+	require.Len(t, bwa.whiteSegments, 7)
+	for rank := range bwa.whiteSegments {
+		assert.Len(t, bwa.whiteSegments[rank].elements, 1<<rank)
+	}
+	bwa.Reserve(200)
+	assert.Len(t, bwa.whiteSegments, 8)
+
+	bwa.Insert(23)
+	bwa.Insert(37)
+	bwa.ShrinkToFit()
+	validateBWArr(t, bwa)
+	require.Len(t, bwa.whiteSegments, 2)
+	assert.Len(t, bwa.whiteSegments[0].elements, 1)
+	assert.Len(t, bwa.whiteSegments[1].elements, 2)
+	assert.Equal(t, 3, bwa.Len())
+
+	bwa.Clear(false)
+	bwa.ShrinkToFit()
+	assert.Empty(t, bwa.whiteSegments)
+	bwa.Insert(17)
+	assert.True(t, bwa.Has(17))
+}
+
+func TestBWArr_CloneKeepsOptions(t *testing.T) {
+	t.Parallel()
+	bwa := NewWithOptions(int64Cmp, 0, Options{ElementsKeepAllocated: 64, ShrinkPolicy: ShrinkNever})
+	clone := bwa.Clone()
+	assert.Equal(t, bwa.maxSegmentRankToKeep, clone.maxSegmentRankToKeep)
+	assert.Equal(t, ShrinkNever, clone.ext.shrinkPolicy)
+	assert.NotSame(t, bwa.ext, clone.ext)
+}
+
 func TestBWArr_DeleteAutoCompactNoEffect(t *testing.T) {
 	t.Parallel()
 	testArray := makeInt64BWAFromWhite([][]int64{{17}, {23, 42}}, 3)
@@ -1294,7 +1469,7 @@ func TestBWArr_DeleteAutoCompactNoEffect(t *testing.T) {
 	assert.Len(t, testArray.whiteSegments[0].elements, 1)
 	// Segment with rank 1 is preserved
 	assert.Len(t, testArray.whiteSegments[1].elements, 2)
-	assert.Len(t, testArray.whiteSegments[1].deleted, 2)
+	assert.Len(t, testArray.whiteSegments[1].deleted, 1) // One bitmap word.
 }
 
 func int64Cmp(a, b int64) int {
@@ -1374,6 +1549,7 @@ func testNewBWArr[T any](t *testing.T, cmp CmpFunc[T]) {
 }
 
 func validateBWArr[T any](t *testing.T, bwa *BWArr[T]) {
+	require.NoError(t, bwa.Validate())
 	if len(bwa.whiteSegments) == 0 || bwa.total == 0 {
 		return
 	}
@@ -1396,7 +1572,7 @@ func makeInt64BWAFromWhite(segs [][]int64, total int) *BWArr[int64] {
 	}
 	for i, seg := range segs {
 		l := len(seg)
-		bwa.whiteSegments[i] = segment[int64]{elements: seg, deleted: make([]bool, l), maxNonDeletedIdx: l - 1}
+		bwa.whiteSegments[i] = segment[int64]{elements: seg, deleted: newDeletedBitmap(l), maxNonDeletedIdx: l - 1}
 	}
 	return &bwa
 }
@@ -1419,7 +1595,7 @@ type bwaIdx struct {
 
 func markDel[T any](bwa *BWArr[T], toDel ...bwaIdx) *BWArr[T] {
 	for i := range toDel {
-		bwa.whiteSegments[toDel[i].segNum].deleted[toDel[i].idx] = true
+		bwa.whiteSegments[toDel[i].segNum].markDeleted(toDel[i].idx)
 		bwa.whiteSegments[toDel[i].segNum].deletedNum++
 	}
 	return bwa
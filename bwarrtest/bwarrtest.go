@@ -0,0 +1,292 @@
+// Package bwarrtest implements model-based testing of bwarr.BWArr. It runs sequences of operations
+// against a BWArr and a naive sorted slice, and reports the first difference, including the order
+// of equal elements. Failing sequences can be shrunk and printed as a Go test reproducing the failure.
+//
+// Use Run in regular tests and Fuzz in fuzz tests. Both accept a Factory, so wrappers around BWArr
+// can check the BWArr configured the way they use it.
+package bwarrtest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dronnix/bwarr"
+)
+
+// keyRange is the number of distinct keys used by operations. It is small, so there are many duplicates.
+const keyRange = 32
+
+// Elem is the element type used by the checks. Elements are ordered by Key only, Seq is the number
+// of the insertion, it is used to verify the order of equal elements.
+type Elem struct {
+	Key int
+	Seq int
+}
+
+// CmpElem compares elements by Key.
+func CmpElem(a, b Elem) int {
+	return a.Key - b.Key
+}
+
+// Factory creates an empty BWArr under test with the given comparison function.
+type Factory func(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem]
+
+// New is the Factory of BWArr created by bwarr.New.
+func New(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+	return bwarr.New(cmp, 0)
+}
+
+// OpKind is the kind of operation.
+type OpKind uint8
+
+// Operations. Key is the argument of the operation. Key2 is the upper bound of range operations;
+// for other iterations, if not zero, it is the number of elements to visit before stopping.
+// OpGrow reserves room for Key*32 more elements, so it reaches larger segments than the elements do.
+const (
+	OpInsert OpKind = iota
+	OpDelete
+	OpDeleteMin
+	OpDeleteMax
+	OpReplaceOrInsert
+	OpGet
+	OpMinMax
+	OpAscend
+	OpAscendGreaterOrEqual
+	OpAscendLessThan
+	OpAscendRange
+	OpDescend
+	OpDescendGreaterOrEqual
+	OpDescendLessThan
+	OpDescendRange
+	OpClone
+	OpClear
+	OpCompact
+	OpRebuild
+	OpShrinkToFit
+	OpGrow
+	numOpKinds
+)
+
+var opNames = [...]string{
+	"OpInsert", "OpDelete", "OpDeleteMin", "OpDeleteMax", "OpReplaceOrInsert", "OpGet", "OpMinMax",
+	"OpAscend", "OpAscendGreaterOrEqual", "OpAscendLessThan", "OpAscendRange",
+	"OpDescend", "OpDescendGreaterOrEqual", "OpDescendLessThan", "OpDescendRange",
+	"OpClone", "OpClear", "OpCompact", "OpRebuild", "OpShrinkToFit", "OpGrow",
+}
+
+func (k OpKind) String() string {
+	if k < numOpKinds {
+		return opNames[k]
+	}
+	return fmt.Sprintf("OpKind(%d)", uint8(k))
+}
+
+// Op is an operation applied to both the BWArr and the model.
+type Op struct {
+	Kind OpKind
+	Key  int
+	Key2 int
+}
+
+// Failure describes the first difference between the BWArr and the model.
+type Failure struct {
+	Step   int // Index of the failed operation, len(ops) if the final check failed.
+	Op     Op
+	Reason string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("bwarrtest: step %d %s{Key: %d, Key2: %d}: %s", f.Step, f.Op.Kind, f.Op.Key, f.Op.Key2, f.Reason)
+}
+
+// RandomOps returns n random operations, most of them are insertions and deletions.
+func RandomOps(r *rand.Rand, n int) []Op {
+	ops := make([]Op, n)
+	for i := range ops {
+		var kind OpKind
+		switch x := r.Intn(100); {
+		case x < 40:
+			kind = OpInsert
+		case x < 60:
+			kind = OpDelete
+		case x < 65:
+			kind = OpDeleteMin
+		case x < 70:
+			kind = OpDeleteMax
+		case x < 98:
+			kind = OpKind(r.Intn(int(OpClone-OpReplaceOrInsert))) + OpReplaceOrInsert
+		default:
+			kind = OpKind(r.Intn(int(numOpKinds-OpClone))) + OpClone
+		}
+		ops[i] = Op{Kind: kind, Key: r.Intn(keyRange), Key2: r.Intn(keyRange)}
+	}
+	return ops
+}
+
+// DecodeOps maps arbitrary bytes, e.g., fuzzer input, to operations, three bytes per operation.
+func DecodeOps(data []byte) []Op {
+	ops := make([]Op, 0, len(data)/3)
+	for ; len(data) >= 3; data = data[3:] {
+		ops = append(ops, Op{
+			Kind: OpKind(data[0] % byte(numOpKinds)),
+			Key:  int(data[1] % keyRange),
+			Key2: int(data[2] % keyRange),
+		})
+	}
+	return ops
+}
+
+// Check runs ops against a BWArr created by New and the model. See CheckWith.
+func Check(ops []Op) error {
+	return CheckWith(New, ops)
+}
+
+// CheckWith runs ops against a BWArr created by newBWArr and the model. After every operation it
+// compares the results and Len, and calls Validate. In the end, it compares all elements.
+// It returns nil or a *Failure; panics of the BWArr are reported as failures too.
+func CheckWith(newBWArr Factory, ops []Op) error {
+	return check(newBWArr, ops, nil)
+}
+
+// CheckHinted is CheckWith that calls Insert, Delete, ReplaceOrInsert, Get and Has through their
+// Hint variants, with one Hint shared by all operations.
+func CheckHinted(newBWArr Factory, ops []Op) error {
+	return check(newBWArr, ops, &bwarr.Hint{}) //nolint:exhaustruct
+}
+
+func check(newBWArr Factory, ops []Op, hint *bwarr.Hint) (err error) {
+	h := harness{bwa: newBWArr(CmpElem), hint: hint} //nolint:exhaustruct
+	step := 0
+	defer func() {
+		if r := recover(); r != nil {
+			f := &Failure{Step: step, Op: Op{}, Reason: fmt.Sprintf("panic: %v", r)} //nolint:exhaustruct
+			// step is len(ops) if the final comparison panicked.
+			if step < len(ops) {
+				f.Op = ops[step]
+			}
+			err = f
+		}
+	}()
+	for ; step < len(ops); step++ {
+		reason := h.apply(ops[step])
+		if reason == "" && h.bwa.Len() != len(h.m.elems) {
+			reason = fmt.Sprintf("Len() = %d, want %d", h.bwa.Len(), len(h.m.elems))
+		}
+		if reason == "" {
+			if err := h.bwa.Validate(); err != nil {
+				reason = err.Error()
+			}
+		}
+		if reason != "" {
+			return &Failure{Step: step, Op: ops[step], Reason: reason}
+		}
+	}
+	if reason := h.compareAll(); reason != "" {
+		return &Failure{Step: len(ops), Op: Op{}, Reason: reason} //nolint:exhaustruct
+	}
+	return nil
+}
+
+// Shrink returns a shorter sequence of operations for which fails still returns true.
+// ops must fail. The result is minimal in the sense that removing any single operation
+// makes it pass.
+func Shrink(ops []Op, fails func([]Op) bool) []Op {
+	ops = slices.Clone(ops)
+	for chunk := max(len(ops)/2, 1); chunk >= 1; chunk /= 2 {
+		for removed := true; removed; {
+			removed = false
+			for i := 0; i+chunk <= len(ops); {
+				candidate := slices.Concat(ops[:i], ops[i+chunk:])
+				if fails(candidate) {
+					ops, removed = candidate, true
+				} else {
+					i += chunk
+				}
+			}
+			// Removing an operation may make earlier ones removable, so single operations
+			// are retried until a pass removes nothing.
+			removed = removed && chunk == 1
+		}
+	}
+	return ops
+}
+
+// Minimize shrinks ops failing CheckWith with newBWArr, see Shrink.
+func Minimize(newBWArr Factory, ops []Op) []Op {
+	if f, ok := CheckWith(newBWArr, ops).(*Failure); ok && f.Step < len(ops) {
+		ops = ops[:f.Step+1]
+	}
+	return Shrink(ops, func(ops []Op) bool { return CheckWith(newBWArr, ops) != nil })
+}
+
+// GoCode returns a Go test function that runs ops with CheckWith. factory is the Go expression
+// of the Factory in the reproducer, for example "bwarrtest.New".
+func GoCode(factory string, ops []Op) string {
+	var sb strings.Builder
+	sb.WriteString("func TestBWArrReproducer(t *testing.T) {\n\tops := []bwarrtest.Op{\n")
+	for _, op := range ops {
+		fmt.Fprintf(&sb, "\t\t{Kind: bwarrtest.%s, Key: %d", op.Kind, op.Key)
+		if op.Key2 != 0 {
+			fmt.Fprintf(&sb, ", Key2: %d", op.Key2)
+		}
+		sb.WriteString("},\n")
+	}
+	fmt.Fprintf(&sb, "\t}\n\tif err := bwarrtest.CheckWith(%s, ops); err != nil {\n\t\tt.Fatal(err)\n\t}\n}\n", factory)
+	return sb.String()
+}
+
+// Run checks numOps random operations generated from seed. On failure it shrinks
+// the operations and fails tb with a reproducer.
+func Run(tb testing.TB, newBWArr Factory, seed int64, numOps int) {
+	tb.Helper()
+	report(tb, newBWArr, RandomOps(rand.New(rand.NewSource(seed)), numOps)) //nolint:gosec
+}
+
+// Fuzz adds seed inputs to f and fuzzes operations decoded by DecodeOps. On failure it
+// shrinks the operations and fails with a reproducer. Call it from a fuzz test:
+//
+//	func FuzzMyBWArr(f *testing.F) {
+//		bwarrtest.Fuzz(f, newMyBWArr)
+//	}
+func Fuzz(f *testing.F, newBWArr Factory) {
+	f.Helper()
+	r := rand.New(rand.NewSource(1)) //nolint:gosec
+	for _, n := range []int{1, 10, 100, 1000} {
+		data := make([]byte, 3*n)
+		r.Read(data)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		report(t, newBWArr, DecodeOps(data))
+	})
+}
+
+func report(tb testing.TB, newBWArr Factory, ops []Op) {
+	tb.Helper()
+	if err := CheckWith(newBWArr, ops); err != nil {
+		minimal := Minimize(newBWArr, ops)
+		tb.Fatalf("%v\nminimal reproducer (%d of %d operations) fails with: %v\n%s",
+			err, len(minimal), len(ops), CheckWith(newBWArr, minimal), GoCode(factoryExpr(newBWArr), minimal))
+	}
+}
+
+// factoryExpr returns the Go expression of newBWArr for a reproducer placed in the package declaring it.
+// Function literals and method values have no usable name, so the reproducer refers to newBWArr,
+// which has to be defined by the user.
+func factoryExpr(newBWArr Factory) string {
+	name := runtime.FuncForPC(reflect.ValueOf(newBWArr).Pointer()).Name()
+	if name == reflect.TypeOf(Elem{}).PkgPath()+".New" { //nolint:exhaustruct
+		return "bwarrtest.New"
+	}
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = name[strings.Index(name, ".")+1:]
+	if strings.ContainsAny(name, ".-[(") { // Closures are named like Func.func1, method values like (*T).M-fm.
+		return "newBWArr"
+	}
+	return name
+}
@@ -0,0 +1,177 @@
+package bwarrtest
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/bwarr"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	for seed := range int64(20) {
+		Run(t, New, seed, 2000)
+	}
+}
+
+func TestRun_WithOptions(t *testing.T) {
+	t.Parallel()
+	newBWArr := func(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+		return bwarr.NewWithOptions(cmp, 100, bwarr.Options{ShrinkPolicy: bwarr.ShrinkHysteresis}) //nolint:exhaustruct
+	}
+	for seed := range int64(5) {
+		Run(t, newBWArr, seed, 2000)
+	}
+}
+
+func TestCheckHinted(t *testing.T) {
+	t.Parallel()
+	for seed := range int64(5) {
+		ops := RandomOps(rand.New(rand.NewSource(seed)), 2000)
+		require.NoError(t, CheckHinted(New, ops), "seed %d", seed)
+	}
+}
+
+func TestRun_BloomFilter(t *testing.T) {
+	t.Parallel()
+	filter := bwarr.BloomFilter(func(e Elem) uint64 { return uint64(e.Key) * 0x9E3779B97F4A7C15 }, 2, 8) //nolint:gosec
+	newBWArr := func(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+		return bwarr.NewWithOptions(cmp, 0, bwarr.Options{Filter: filter}) //nolint:exhaustruct
+	}
+	for seed := range int64(5) {
+		Run(t, newBWArr, seed, 2000)
+		require.NoError(t, CheckHinted(newBWArr, RandomOps(rand.New(rand.NewSource(seed)), 2000)), "seed %d", seed)
+	}
+}
+
+func TestRun_FractionalCascading(t *testing.T) {
+	t.Parallel()
+	for _, policy := range []bwarr.ShrinkPolicy{bwarr.ShrinkEager, bwarr.ShrinkNever, bwarr.ShrinkHysteresis} {
+		newBWArr := func(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+			return bwarr.NewWithOptions(cmp, 0, bwarr.Options{FractionalCascading: true, ShrinkPolicy: policy}) //nolint:exhaustruct
+		}
+		for seed := range int64(5) {
+			Run(t, newBWArr, seed, 2000)
+			require.NoError(t, CheckHinted(newBWArr, RandomOps(rand.New(rand.NewSource(seed)), 2000)), "seed %d", seed)
+		}
+	}
+}
+
+func TestRun_SearchIndex(t *testing.T) {
+	t.Parallel()
+	newBWArr := func(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+		return bwarr.NewWithOptions(cmp, 0, bwarr.Options{SearchIndexMinRank: 1}) //nolint:exhaustruct
+	}
+	for seed := range int64(5) {
+		Run(t, newBWArr, seed, 2000)
+	}
+}
+
+func FuzzBWArr(f *testing.F) {
+	Fuzz(f, New)
+}
+
+// newBrokenBWArr returns a BWArr with a comparison function that treats keys 7 and 8 as equal,
+// so the BWArr diverges from the model.
+func newBrokenBWArr(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+	return bwarr.New(func(a, b Elem) int {
+		if a.Key == 8 {
+			a.Key = 7
+		}
+		if b.Key == 8 {
+			b.Key = 7
+		}
+		return cmp(a, b)
+	}, 0)
+}
+
+func TestMinimize(t *testing.T) {
+	t.Parallel()
+	ops := RandomOps(rand.New(rand.NewSource(1)), 1000)
+	err := CheckWith(newBrokenBWArr, ops)
+	var failure *Failure
+	require.ErrorAs(t, err, &failure)
+
+	minimal := Minimize(newBrokenBWArr, ops)
+	require.Error(t, CheckWith(newBrokenBWArr, minimal))
+	assert.LessOrEqual(t, len(minimal), 3, "two insertions and a lookup are enough: %v", minimal)
+	for i := range minimal {
+		shorter := append(append([]Op{}, minimal[:i]...), minimal[i+1:]...)
+		assert.NoError(t, CheckWith(newBrokenBWArr, shorter), "removing operation %d must fix the failure", i)
+	}
+}
+
+func TestShrink_RetriesEarlierOperations(t *testing.T) {
+	t.Parallel()
+	has := func(ops []Op, key int) bool {
+		return slices.ContainsFunc(ops, func(op Op) bool { return op.Key == key })
+	}
+	// Key 1 can be removed only after key 2 is removed.
+	fails := func(ops []Op) bool { return has(ops, 9) && (has(ops, 1) || !has(ops, 2)) }
+	ops := []Op{{Kind: OpInsert, Key: 1}, {Kind: OpInsert, Key: 2}, {Kind: OpInsert, Key: 9}}
+	assert.Equal(t, []Op{{Kind: OpInsert, Key: 9}}, Shrink(ops, fails))
+}
+
+func TestCheck_ReportsPanic(t *testing.T) {
+	t.Parallel()
+	newPanicking := func(bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] {
+		return bwarr.New(func(a, b Elem) int {
+			if a.Key == 5 || b.Key == 5 {
+				panic("boom")
+			}
+			return a.Key - b.Key
+		}, 0)
+	}
+	err := CheckWith(newPanicking, []Op{{Kind: OpInsert, Key: 1}, {Kind: OpInsert, Key: 5}})
+	var failure *Failure
+	require.ErrorAs(t, err, &failure)
+	assert.Equal(t, 1, failure.Step)
+	assert.Contains(t, failure.Reason, "panic: boom")
+
+	// Inserting a single element compares nothing, Get(5) of the final comparison panics.
+	err = CheckWith(newPanicking, []Op{{Kind: OpInsert, Key: 1}})
+	require.ErrorAs(t, err, &failure)
+	assert.Equal(t, 1, failure.Step)
+	assert.Equal(t, Op{}, failure.Op) //nolint:exhaustruct
+	assert.Contains(t, failure.Reason, "panic: boom")
+}
+
+func TestGoCode(t *testing.T) {
+	t.Parallel()
+	code := GoCode("newMyBWArr", []Op{{Kind: OpInsert, Key: 3}, {Kind: OpAscendRange, Key: 1, Key2: 5}})
+	assert.Equal(t, `func TestBWArrReproducer(t *testing.T) {
+	ops := []bwarrtest.Op{
+		{Kind: bwarrtest.OpInsert, Key: 3},
+		{Kind: bwarrtest.OpAscendRange, Key: 1, Key2: 5},
+	}
+	if err := bwarrtest.CheckWith(newMyBWArr, ops); err != nil {
+		t.Fatal(err)
+	}
+}
+`, code)
+
+	assert.Equal(t, "bwarrtest.New", factoryExpr(New))
+	assert.Equal(t, "newBrokenBWArr", factoryExpr(newBrokenBWArr))
+	assert.Equal(t, "newBWArr", factoryExpr(func(cmp bwarr.CmpFunc[Elem]) *bwarr.BWArr[Elem] { return bwarr.New(cmp, 0) }))
+}
+
+func TestRandomOps_AllKinds(t *testing.T) {
+	t.Parallel()
+	seen := make(map[OpKind]bool)
+	for _, op := range RandomOps(rand.New(rand.NewSource(1)), 10000) {
+		seen[op.Kind] = true
+	}
+	for kind := range numOpKinds {
+		assert.True(t, seen[kind], "%s is never generated", kind)
+	}
+}
+
+func TestDecodeOps(t *testing.T) {
+	t.Parallel()
+	ops := DecodeOps([]byte{byte(OpDelete), 33, 2, byte(numOpKinds), 4, 5, 42})
+	assert.Equal(t, []Op{{Kind: OpDelete, Key: 1, Key2: 2}, {Kind: OpInsert, Key: 4, Key2: 5}}, ops)
+}
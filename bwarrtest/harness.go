@@ -0,0 +1,230 @@
+package bwarrtest
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/dronnix/bwarr"
+)
+
+type harness struct {
+	bwa  *bwarr.BWArr[Elem]
+	hint *bwarr.Hint // If not nil, point operations go through the Hint variants.
+	m    model
+	seq  int
+}
+
+// apply runs op against the BWArr and the model and returns the description of a difference, or "".
+func (h *harness) apply(op Op) string {
+	key := Elem{Key: op.Key, Seq: 0}
+	switch op.Kind {
+	case OpInsert:
+		e := Elem{Key: op.Key, Seq: h.seq}
+		h.seq++
+		h.insert(e)
+		h.m.insert(e)
+	case OpDelete:
+		got, found := h.delete(key)
+		want, wantFound := Elem{}, false //nolint:exhaustruct
+		if i := h.m.get(op.Key); i >= 0 {
+			want, wantFound = h.m.remove(i), true
+		}
+		return diffResult("Delete", got, found, want, wantFound)
+	case OpDeleteMin:
+		got, found := h.bwa.DeleteMin()
+		want, wantFound := Elem{}, false //nolint:exhaustruct
+		if len(h.m.elems) > 0 {
+			want, wantFound = h.m.remove(0), true
+		}
+		return diffResult("DeleteMin", got, found, want, wantFound)
+	case OpDeleteMax:
+		got, found := h.bwa.DeleteMax()
+		want, wantFound := Elem{}, false //nolint:exhaustruct
+		if len(h.m.elems) > 0 {
+			want, wantFound = h.m.remove(h.m.lowerBound(h.m.elems[len(h.m.elems)-1].Key)), true
+		}
+		return diffResult("DeleteMax", got, found, want, wantFound)
+	case OpReplaceOrInsert:
+		e := Elem{Key: op.Key, Seq: h.seq}
+		h.seq++
+		got, found := h.replaceOrInsert(e)
+		want, wantFound := Elem{}, false //nolint:exhaustruct
+		if i := h.m.get(op.Key); i >= 0 {
+			want, wantFound = h.m.elems[i], true
+			h.m.elems[i] = e // The new element takes the place of the replaced one in the FIFO order.
+		} else {
+			h.m.insert(e)
+		}
+		return diffResult("ReplaceOrInsert", got, found, want, wantFound)
+	case OpGet:
+		return h.checkGet(op.Key)
+	case OpMinMax:
+		return h.checkMinMax()
+	case OpAscend, OpAscendGreaterOrEqual, OpAscendLessThan, OpAscendRange,
+		OpDescend, OpDescendGreaterOrEqual, OpDescendLessThan, OpDescendRange:
+		return h.checkIteration(op)
+	case OpClone:
+		old := h.bwa
+		h.bwa = old.Clone()
+		// Modifications of the original must not affect the clone.
+		old.Insert(Elem{Key: op.Key, Seq: -1})
+		old.DeleteMin()
+		old.DeleteMax()
+		return h.compareAll()
+	case OpClear:
+		h.bwa.Clear(op.Key%2 == 0)
+		h.m.elems = h.m.elems[:0]
+	case OpCompact:
+		h.bwa.Compact()
+	case OpRebuild:
+		h.bwa.Rebuild()
+	case OpShrinkToFit:
+		h.bwa.ShrinkToFit()
+	case OpGrow:
+		h.bwa.Grow(op.Key * keyRange)
+	case numOpKinds:
+	default:
+		return fmt.Sprintf("unknown operation %d", op.Kind)
+	}
+	return ""
+}
+
+func (h *harness) insert(e Elem) {
+	if h.hint != nil {
+		h.bwa.InsertHint(e, h.hint)
+		return
+	}
+	h.bwa.Insert(e)
+}
+
+func (h *harness) delete(e Elem) (Elem, bool) {
+	if h.hint != nil {
+		return h.bwa.DeleteHint(e, h.hint)
+	}
+	return h.bwa.Delete(e)
+}
+
+func (h *harness) replaceOrInsert(e Elem) (Elem, bool) {
+	if h.hint != nil {
+		return h.bwa.ReplaceOrInsertHint(e, h.hint)
+	}
+	return h.bwa.ReplaceOrInsert(e)
+}
+
+func (h *harness) get(e Elem) (Elem, bool) {
+	if h.hint != nil {
+		return h.bwa.GetHint(e, h.hint)
+	}
+	return h.bwa.Get(e)
+}
+
+func (h *harness) has(e Elem) bool {
+	if h.hint != nil {
+		return h.bwa.HasHint(e, h.hint)
+	}
+	return h.bwa.Has(e)
+}
+
+// checkGet checks that Get and Has find the oldest element with the key.
+func (h *harness) checkGet(key int) string {
+	got, found := h.get(Elem{Key: key, Seq: 0})
+	want, wantFound := Elem{}, false //nolint:exhaustruct
+	if i := h.m.get(key); i >= 0 {
+		want, wantFound = h.m.elems[i], true
+	}
+	if diff := diffResult("Get", got, found, want, wantFound); diff != "" {
+		return diff
+	}
+	if has := h.has(Elem{Key: key, Seq: 0}); has != wantFound {
+		return fmt.Sprintf("Has(%d) = %t, want %t", key, has, wantFound)
+	}
+	return ""
+}
+
+// checkMinMax checks that Min and Max return the oldest of the smallest and the largest elements.
+func (h *harness) checkMinMax() string {
+	gotMin, foundMin := h.bwa.Min()
+	gotMax, foundMax := h.bwa.Max()
+	wantMin, wantMax, wantFound := Elem{}, Elem{}, len(h.m.elems) > 0 //nolint:exhaustruct
+	if wantFound {
+		wantMin = h.m.elems[0]
+		wantMax = h.m.elems[h.m.lowerBound(h.m.elems[len(h.m.elems)-1].Key)]
+	}
+	if diff := diffResult("Min", gotMin, foundMin, wantMin, wantFound); diff != "" {
+		return diff
+	}
+	return diffResult("Max", gotMax, foundMax, wantMax, wantFound)
+}
+
+// checkIteration compares keys of visited elements. Equal elements are visited in the order
+// of the internal layout, so only their number is compared.
+func (h *harness) checkIteration(op Op) string {
+	limit := op.Key2
+	var got []int
+	iter := func(e Elem) bool {
+		got = append(got, e.Key)
+		return limit == 0 || len(got) < limit
+	}
+	lo, hi := Elem{Key: op.Key, Seq: 0}, Elem{Key: op.Key2, Seq: 0}
+	from, to, desc := 0, len(h.m.elems), false
+	switch op.Kind { //nolint:exhaustive
+	case OpAscend:
+		h.bwa.Ascend(iter)
+	case OpAscendGreaterOrEqual:
+		h.bwa.AscendGreaterOrEqual(lo, iter)
+		from = h.m.lowerBound(op.Key)
+	case OpAscendLessThan:
+		h.bwa.AscendLessThan(lo, iter)
+		to = h.m.lowerBound(op.Key)
+	case OpAscendRange:
+		limit = 0
+		h.bwa.AscendRange(lo, hi, iter)
+		from, to = h.m.lowerBound(op.Key), h.m.lowerBound(op.Key2)
+	case OpDescend:
+		desc = true
+		h.bwa.Descend(iter)
+	case OpDescendGreaterOrEqual:
+		desc = true
+		h.bwa.DescendGreaterOrEqual(lo, iter)
+		from = h.m.lowerBound(op.Key)
+	case OpDescendLessThan:
+		desc = true
+		h.bwa.DescendLessThan(lo, iter)
+		to = h.m.lowerBound(op.Key)
+	case OpDescendRange:
+		limit, desc = 0, true
+		h.bwa.DescendRange(lo, hi, iter)
+		from, to = h.m.lowerBound(op.Key), h.m.lowerBound(op.Key2)
+	}
+	want := h.m.keys(from, to, desc)
+	if limit != 0 && len(want) > limit {
+		want = want[:limit]
+	}
+	if !slices.Equal(got, want) {
+		return fmt.Sprintf("%s visited keys %v, want %v", op.Kind, got, want)
+	}
+	return ""
+}
+
+// compareAll compares all keys in ascending order and, for every key, the oldest element.
+func (h *harness) compareAll() string {
+	if h.bwa.Len() != len(h.m.elems) {
+		return fmt.Sprintf("Len() = %d, want %d", h.bwa.Len(), len(h.m.elems))
+	}
+	if diff := h.checkIteration(Op{Kind: OpAscend, Key: 0, Key2: 0}); diff != "" {
+		return diff
+	}
+	for key := range keyRange {
+		if diff := h.checkGet(key); diff != "" {
+			return diff
+		}
+	}
+	return ""
+}
+
+func diffResult(method string, got Elem, found bool, want Elem, wantFound bool) string {
+	if found != wantFound || got != want {
+		return fmt.Sprintf("%s returned %+v, %t, want %+v, %t", method, got, found, want, wantFound)
+	}
+	return ""
+}
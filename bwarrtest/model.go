@@ -0,0 +1,53 @@
+package bwarrtest
+
+import "sort"
+
+// model is a naive reference implementation: a slice sorted by Key, equal elements in insertion order.
+type model struct {
+	elems []Elem
+}
+
+// lowerBound returns the index of the first (the oldest) element with the key or greater.
+func (m *model) lowerBound(key int) int {
+	return sort.Search(len(m.elems), func(i int) bool { return m.elems[i].Key >= key })
+}
+
+// upperBound returns the index of the first element with a greater key.
+func (m *model) upperBound(key int) int {
+	return sort.Search(len(m.elems), func(i int) bool { return m.elems[i].Key > key })
+}
+
+func (m *model) insert(e Elem) {
+	i := m.upperBound(e.Key)
+	m.elems = append(m.elems, Elem{}) //nolint:exhaustruct
+	copy(m.elems[i+1:], m.elems[i:])
+	m.elems[i] = e
+}
+
+// get returns the index of the oldest element with the key, or -1.
+func (m *model) get(key int) int {
+	if i := m.lowerBound(key); i < len(m.elems) && m.elems[i].Key == key {
+		return i
+	}
+	return -1
+}
+
+func (m *model) remove(i int) Elem {
+	e := m.elems[i]
+	m.elems = append(m.elems[:i], m.elems[i+1:]...)
+	return e
+}
+
+// keys returns the keys of the elements in [from, to) in ascending or descending order.
+func (m *model) keys(from, to int, desc bool) []int {
+	res := make([]int, 0, max(to-from, 0))
+	for i := from; i < to; i++ {
+		res = append(res, m.elems[i].Key)
+	}
+	if desc {
+		for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+			res[i], res[j] = res[j], res[i]
+		}
+	}
+	return res
+}
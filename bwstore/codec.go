@@ -0,0 +1,9 @@
+package bwstore
+
+// Codec converts elements to and from the binary representation stored in run files.
+type Codec[T any] interface {
+	// Append appends the binary representation of v to dst and returns the extended buffer.
+	Append(dst []byte, v T) []byte
+	// Decode restores an element from the bytes produced by Append. It must not retain src.
+	Decode(src []byte) (T, error)
+}
@@ -0,0 +1,144 @@
+package bwstore
+
+import (
+	"slices"
+
+	"github.com/dronnix/bwarr"
+)
+
+// source is a sorted stream of records. Equal records come from the oldest to the newest.
+type source[T any] interface {
+	peek() (record[T], bool)
+	advance() error
+}
+
+// memBatch is the number of records memSource copies out of the in-memory BWArr at once.
+const memBatch = 64
+
+// memSource streams records from the in-memory BWArr. It copies them in small batches, each one starting
+// after the last copied entry, so a point lookup does not copy the whole in-memory part.
+type memSource[T any] struct {
+	mem     *bwarr.BWArr[memEntry[T]]
+	from    memEntry[T] // If seek is set, the first batch starts at the first entry greater or equal to from,
+	seek    bool        // or at the last entry less than from if desc is set.
+	desc    bool
+	buf     []memEntry[T]
+	batch   []memEntry[T] // Not consumed part of buf.
+	last    memEntry[T]   // The last entry of buf.
+	started bool
+	done    bool // buf holds the last batch.
+}
+
+func (s *memSource[T]) peek() (record[T], bool) {
+	if len(s.batch) == 0 {
+		s.fill()
+		if len(s.batch) == 0 {
+			return record[T]{}, false //nolint:exhaustruct
+		}
+	}
+	return s.batch[0].rec, true
+}
+
+func (s *memSource[T]) advance() error {
+	s.batch = s.batch[1:]
+	return nil
+}
+
+func (s *memSource[T]) fill() {
+	if s.done {
+		return
+	}
+	s.buf = s.buf[:0]
+	collect := func(e memEntry[T]) bool {
+		s.buf = append(s.buf, e)
+		return len(s.buf) < memBatch
+	}
+	switch {
+	case !s.started && s.desc && s.seek:
+		s.mem.DescendLessThan(s.from, collect)
+	case !s.started && s.desc:
+		s.mem.Descend(collect)
+	case !s.started && s.seek:
+		s.mem.AscendGreaterOrEqual(s.from, collect)
+	case !s.started:
+		s.mem.Ascend(collect)
+	case s.desc:
+		s.mem.DescendLessThan(s.last, collect)
+	default: // Sequence numbers are unique, so no entry lies between the last one and its successor.
+		s.mem.AscendGreaterOrEqual(memEntry[T]{rec: s.last.rec, seq: s.last.seq + 1}, collect)
+	}
+	s.started, s.done = true, len(s.buf) < memBatch
+	if len(s.buf) > 0 {
+		s.last = s.buf[len(s.buf)-1]
+	}
+	s.batch = s.buf
+}
+
+// merger is a k-way merge over sources ordered from the oldest to the newest,
+// the same way BWArr orders segments from the highest rank to the lowest.
+// If desc is set, the sources stream records in descending order, equal ones from the newest to the oldest.
+type merger[T any] struct {
+	srcs []source[T]
+	cmp  bwarr.CmpFunc[T]
+	desc bool
+}
+
+// nextGroup appends to buf all records equal to the smallest key among the sources (the greatest one
+// if desc is set), from the oldest to the newest. Returns buf unchanged if all sources are exhausted.
+func (m *merger[T]) nextGroup(buf []record[T]) ([]record[T], error) {
+	first := -1
+	var next record[T]
+	for i, src := range m.srcs {
+		rec, ok := src.peek()
+		if !ok {
+			continue
+		}
+		if first < 0 {
+			first, next = i, rec
+			continue
+		}
+		// Strict comparison keeps the oldest source for equal keys.
+		if c := m.cmp(rec.val, next.val); (c < 0 && !m.desc) || (c > 0 && m.desc) {
+			first, next = i, rec
+		}
+	}
+	if first < 0 {
+		return buf, nil
+	}
+	for _, src := range m.srcs[first:] {
+		from := len(buf)
+		for rec, ok := src.peek(); ok && m.cmp(rec.val, next.val) == 0; rec, ok = src.peek() {
+			buf = append(buf, rec)
+			if err := src.advance(); err != nil {
+				return buf, err
+			}
+		}
+		if m.desc {
+			slices.Reverse(buf[from:])
+		}
+	}
+	return buf, nil
+}
+
+// resolveGroup returns live elements of a group of equal records ordered from the oldest
+// to the newest. Every tombstone cancels the oldest live element that is still visible,
+// so it is enough to skip as many oldest live elements as there are tombstones.
+func resolveGroup[T any](group []record[T], dst []T) []T {
+	tombs := 0
+	for i := range group {
+		if group[i].tomb {
+			tombs++
+		}
+	}
+	for i := range group {
+		if group[i].tomb {
+			continue
+		}
+		if tombs > 0 {
+			tombs--
+			continue
+		}
+		dst = append(dst, group[i].val)
+	}
+	return dst
+}
@@ -0,0 +1,377 @@
+package bwstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dronnix/bwarr"
+)
+
+// Run file layout (all integers are little-endian):
+//
+//	records:      [kind byte][uvarint payload length][payload] ...
+//	offset table: one uint64 per record, the offset of the record in the file
+//	footer:       count | lives | tombstones | offset table position (uint64 each) | magic
+//
+// Records are sorted by CmpFunc; equal records are stored from the oldest to the newest.
+const (
+	runMagic      = "BWS1"
+	runExt        = ".bws"
+	runTmpExt     = ".tmp"
+	runFooterSize = 4*8 + 4
+	maxRecordHead = 1 + binary.MaxVarintLen64
+
+	recordLive byte = 0
+	recordTomb byte = 1
+)
+
+var errCorruptedRun = errors.New("bwstore: corrupted run file")
+
+type record[T any] struct {
+	val  T
+	tomb bool // Tombstone: cancels the oldest live equal element stored in older records.
+}
+
+// run is an immutable sorted file that holds the records of one rank.
+type run[T any] struct {
+	rank     int
+	path     string
+	f        *os.File
+	count    int   // Number of records, including tombstones.
+	lives    int   // Number of live records.
+	tombs    int   // Number of tombstones.
+	tableOff int64 // Position of the offset table.
+}
+
+func runFileName(rank int) string {
+	return fmt.Sprintf("%02d%s", rank, runExt)
+}
+
+// parseRunFileName returns the rank encoded in a run file name, or -1 if name is not a run file.
+func parseRunFileName(name string) int {
+	if !strings.HasSuffix(name, runExt) {
+		return -1
+	}
+	rank, err := strconv.Atoi(strings.TrimSuffix(name, runExt))
+	if err != nil || rank < 0 {
+		return -1
+	}
+	return rank
+}
+
+func openRun[T any](path string, rank int) (*run[T], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.Size() < int64(runFooterSize) {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s is too short", errCorruptedRun, path)
+	}
+	var footer [runFooterSize]byte
+	if _, err = f.ReadAt(footer[:], st.Size()-runFooterSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if string(footer[32:]) != runMagic {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s has bad magic", errCorruptedRun, path)
+	}
+	count, tableOff := binary.LittleEndian.Uint64(footer[0:]), binary.LittleEndian.Uint64(footer[24:])
+	if count > uint64(st.Size())/8 || tableOff+count*8+runFooterSize != uint64(st.Size()) { //nolint:gosec
+		f.Close()
+		return nil, fmt.Errorf("%w: %s has inconsistent footer", errCorruptedRun, path)
+	}
+	return &run[T]{
+		rank:     rank,
+		path:     path,
+		f:        f,
+		count:    int(count),                                   //nolint:gosec // Bounded by the file size above.
+		lives:    int(binary.LittleEndian.Uint64(footer[8:])),  //nolint:gosec
+		tombs:    int(binary.LittleEndian.Uint64(footer[16:])), //nolint:gosec
+		tableOff: int64(tableOff),                              //nolint:gosec // Bounded by the file size above.
+	}, nil
+}
+
+func (r *run[T]) close() error {
+	return r.f.Close()
+}
+
+// offset returns the position of the i-th record in the file.
+func (r *run[T]) offset(i int) (int64, error) {
+	var b [8]byte
+	if _, err := r.f.ReadAt(b[:], r.tableOff+int64(i)*8); err != nil {
+		return 0, err
+	}
+	off := binary.LittleEndian.Uint64(b[:])
+	if off >= uint64(r.tableOff) { //nolint:gosec
+		return 0, errCorruptedRun
+	}
+	return int64(off), nil //nolint:gosec
+}
+
+// readAt reads the i-th record with random access.
+func (r *run[T]) readAt(i int, codec Codec[T]) (rec record[T], err error) {
+	off, err := r.offset(i)
+	if err != nil {
+		return rec, err
+	}
+	var head [maxRecordHead]byte
+	n, err := r.f.ReadAt(head[:], off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return rec, err
+	}
+	if n < 2 { //nolint:mnd // Kind byte and at least one byte of length.
+		return rec, errCorruptedRun
+	}
+	size, sizeLen := binary.Uvarint(head[1:n])
+	if sizeLen <= 0 || size > uint64(max(r.tableOff-off-1-int64(sizeLen), 0)) { //nolint:gosec
+		return rec, errCorruptedRun
+	}
+	payload := make([]byte, size)
+	if _, err = r.f.ReadAt(payload, off+1+int64(sizeLen)); err != nil {
+		return rec, err
+	}
+	return decodeRecord(head[0], payload, codec)
+}
+
+// search returns the index of the first record that is greater or equal to val (greater than val
+// if strict is set), or r.count if there is no such record.
+func (r *run[T]) search(val T, strict bool, cmp bwarr.CmpFunc[T], codec Codec[T]) (int, error) {
+	b, e := 0, r.count
+	for b < e {
+		m := (b + e) >> 1
+		rec, err := r.readAt(m, codec)
+		if err != nil {
+			return 0, err
+		}
+		if c := cmp(rec.val, val); c < 0 || (strict && c == 0) {
+			b = m + 1
+		} else {
+			e = m
+		}
+	}
+	return b, nil
+}
+
+// cursor returns a sequential reader positioned at the i-th record.
+func (r *run[T]) cursor(i int, codec Codec[T]) (*runCursor[T], error) {
+	c := &runCursor[T]{codec: codec, left: r.count - i}
+	if c.left == 0 {
+		return c, nil
+	}
+	off, err := r.offset(i)
+	if err != nil {
+		return nil, err
+	}
+	c.avail = r.tableOff - off
+	c.r = bufio.NewReader(io.NewSectionReader(r.f, off, c.avail))
+	return c, c.advance()
+}
+
+// reverseCursor returns a reader of the records in descending order, starting from the one before the i-th.
+func (r *run[T]) reverseCursor(i int, codec Codec[T]) (*runReverseCursor[T], error) {
+	c := &runReverseCursor[T]{run: r, codec: codec, next: i} //nolint:exhaustruct
+	return c, c.advance()
+}
+
+func decodeRecord[T any](kind byte, payload []byte, codec Codec[T]) (rec record[T], err error) {
+	switch kind {
+	case recordLive:
+	case recordTomb:
+		rec.tomb = true
+	default:
+		return rec, fmt.Errorf("%w: unknown record kind %d", errCorruptedRun, kind)
+	}
+	rec.val, err = codec.Decode(payload)
+	return rec, err
+}
+
+// runCursor reads the records of a run one by one in ascending order.
+type runCursor[T any] struct {
+	r     *bufio.Reader
+	codec Codec[T]
+	cur   record[T]
+	valid bool
+	left  int
+	avail int64 // Bytes left before the offset table, bounds the lengths read from the file.
+	buf   []byte
+}
+
+func (c *runCursor[T]) peek() (record[T], bool) {
+	return c.cur, c.valid
+}
+
+func (c *runCursor[T]) advance() error {
+	if c.left == 0 {
+		c.valid = false
+		return nil
+	}
+	kind, err := c.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	size, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return err
+	}
+	c.avail -= 1 + int64(uvarintLen(size))
+	if size > uint64(max(c.avail, 0)) {
+		return errCorruptedRun
+	}
+	c.avail -= int64(size) //nolint:gosec // Bounded by avail above.
+	if uint64(cap(c.buf)) < size {
+		c.buf = make([]byte, size)
+	}
+	c.buf = c.buf[:size]
+	if _, err = io.ReadFull(c.r, c.buf); err != nil {
+		return err
+	}
+	if c.cur, err = decodeRecord(kind, c.buf, c.codec); err != nil {
+		return err
+	}
+	c.valid = true
+	c.left--
+	return nil
+}
+
+// runReverseCursor reads the records of a run one by one in descending order.
+// It reads every record with random access, so it is slower than runCursor.
+type runReverseCursor[T any] struct {
+	run   *run[T]
+	codec Codec[T]
+	cur   record[T]
+	valid bool
+	next  int // Index of the record after the current one in descending order.
+}
+
+func (c *runReverseCursor[T]) peek() (record[T], bool) {
+	return c.cur, c.valid
+}
+
+func (c *runReverseCursor[T]) advance() (err error) {
+	if c.next == 0 {
+		c.valid = false
+		return nil
+	}
+	c.next--
+	c.cur, err = c.run.readAt(c.next, c.codec)
+	c.valid = err == nil
+	return err
+}
+
+func uvarintLen(v uint64) int {
+	return (bits.Len64(v|1) + 6) / 7 //nolint:mnd // 7 bits per byte.
+}
+
+// runWriter writes a new run into a temporary file and publishes it atomically on finish.
+type runWriter[T any] struct {
+	f       *os.File
+	w       *bufio.Writer
+	codec   Codec[T]
+	dir     string
+	rank    int
+	off     int64
+	offsets []uint64
+	lives   int
+	tombs   int
+	buf     []byte
+}
+
+func createRun[T any](dir string, rank int, codec Codec[T]) (*runWriter[T], error) {
+	f, err := os.Create(filepath.Join(dir, runFileName(rank)+runTmpExt))
+	if err != nil {
+		return nil, err
+	}
+	return &runWriter[T]{f: f, w: bufio.NewWriter(f), codec: codec, dir: dir, rank: rank}, nil //nolint:exhaustruct
+}
+
+func (w *runWriter[T]) write(rec record[T]) error {
+	kind := recordLive
+	if rec.tomb {
+		kind = recordTomb
+		w.tombs++
+	} else {
+		w.lives++
+	}
+	w.buf = w.codec.Append(w.buf[:0], rec.val)
+	var head [maxRecordHead]byte
+	head[0] = kind
+	n := 1 + binary.PutUvarint(head[1:], uint64(len(w.buf)))
+	if _, err := w.w.Write(head[:n]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+	w.offsets = append(w.offsets, uint64(w.off)) //nolint:gosec
+	w.off += int64(n + len(w.buf))
+	return nil
+}
+
+// finish writes the offset table and the footer, syncs the file and renames it to its final name.
+// The temporary file is removed on failure.
+func (w *runWriter[T]) finish() (*run[T], error) {
+	var b [8]byte
+	for _, off := range w.offsets {
+		binary.LittleEndian.PutUint64(b[:], off)
+		if _, err := w.w.Write(b[:]); err != nil {
+			return nil, w.abort(err)
+		}
+	}
+	var footer [runFooterSize]byte
+	binary.LittleEndian.PutUint64(footer[0:], uint64(len(w.offsets)))
+	binary.LittleEndian.PutUint64(footer[8:], uint64(w.lives))  //nolint:gosec
+	binary.LittleEndian.PutUint64(footer[16:], uint64(w.tombs)) //nolint:gosec
+	binary.LittleEndian.PutUint64(footer[24:], uint64(w.off))   //nolint:gosec
+	copy(footer[32:], runMagic)
+	if _, err := w.w.Write(footer[:]); err != nil {
+		return nil, w.abort(err)
+	}
+	if err := w.w.Flush(); err != nil {
+		return nil, w.abort(err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return nil, w.abort(err)
+	}
+	if err := w.f.Close(); err != nil {
+		os.Remove(w.f.Name())
+		return nil, err
+	}
+	path := filepath.Join(w.dir, runFileName(w.rank))
+	if err := os.Rename(w.f.Name(), path); err != nil {
+		os.Remove(w.f.Name())
+		return nil, err
+	}
+	if err := syncDir(w.dir); err != nil { // Makes the rename durable.
+		return nil, err
+	}
+	return openRun[T](path, w.rank)
+}
+
+func (w *runWriter[T]) abort(err error) error {
+	w.f.Close()
+	os.Remove(w.f.Name())
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(d.Sync(), d.Close())
+}
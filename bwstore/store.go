@@ -0,0 +1,554 @@
+// Package bwstore implements a disk-backed ordered collection on top of BWArr.
+//
+// The binary-counter segment scheme of BWArr is an LSM tree held in memory. Store keeps
+// the low ranks in an in-memory BWArr and persists every higher rank as an immutable
+// sorted run file. When the in-memory part fills up, it is merged with the runs of the
+// lower ranks into the first free rank, following the same rules as BWArr.Insert.
+// Reads are served through a k-way merge over memory and disk.
+//
+// Deletions are recorded as tombstones that cancel the oldest equal live element in
+// older runs, which preserves the FIFO semantics of BWArr for equal elements. Tombstones
+// are dropped when they reach the run of the highest rank.
+//
+// Runs are published with an atomic rename, but a crash in the middle of a merge may
+// leave both the merged run and its sources on disk. Use a write-ahead log if the store
+// must survive crashes without duplicates.
+package bwstore
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dronnix/bwarr"
+)
+
+const defaultMemElements = 1 << 12
+
+// Options configures a Store.
+type Options struct {
+	// Number of elements (including tombstones) kept in memory before they are flushed to disk.
+	// Will be rounded up to the nearest power of 2. Zero means 4096.
+	MemElements int
+}
+
+// Store is a disk-backed ordered collection with the semantics of BWArr.
+// Store is not safe for concurrent use.
+type Store[T any] struct {
+	dir     string
+	cmp     bwarr.CmpFunc[T]
+	codec   Codec[T]
+	mem     *bwarr.BWArr[memEntry[T]]
+	memCap  int
+	memRank int       // Rank of the run created from the in-memory part.
+	runs    []*run[T] // Indexed by rank, nil if there is no run of this rank.
+	seq     uint64    // Insertion sequence of the in-memory entries.
+	live    int       // Number of live elements in memory and on disk.
+}
+
+// memEntry is an in-memory record. The sequence number orders equal entries from the oldest to the newest.
+type memEntry[T any] struct {
+	rec record[T]
+	seq uint64
+}
+
+// Open opens the store located in dir, creating the directory if needed.
+// The codec is used to write and read elements of the runs.
+func Open[T any](dir string, cmp bwarr.CmpFunc[T], codec Codec[T], opts Options) (*Store[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:mnd
+		return nil, err
+	}
+	memCap := defaultMemElements
+	if opts.MemElements > 0 {
+		memCap = 1 << bits.Len(uint(opts.MemElements-1)) //nolint:gosec
+	}
+	s := &Store[T]{
+		dir:     dir,
+		cmp:     cmp,
+		codec:   codec,
+		memCap:  memCap,
+		memRank: bits.TrailingZeros(uint(memCap)), //nolint:gosec
+		mem: bwarr.New(func(a, b memEntry[T]) int {
+			if c := cmp(a.rec.val, b.rec.val); c != 0 {
+				return c
+			}
+			return compareSeq(a.seq, b.seq)
+		}, memCap),
+	}
+	if err := s.loadRuns(); err != nil {
+		s.closeRuns()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store[T]) loadRuns() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, runTmpExt) { // Leftover of an interrupted merge.
+			if err = os.Remove(filepath.Join(s.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		rank := parseRunFileName(name)
+		if rank < 0 {
+			continue
+		}
+		r, err := openRun[T](filepath.Join(s.dir, name), rank)
+		if err != nil {
+			return err
+		}
+		s.setRun(rank, r)
+		s.live += r.lives - r.tombs
+	}
+	// The store may have been created with larger MemElements: lower ranks are always newer,
+	// so the in-memory part must not produce runs below the existing ones.
+	for rank := 0; rank < s.memRank && rank < len(s.runs); rank++ {
+		if s.runs[rank] != nil {
+			s.memRank, s.memCap = rank, 1<<rank
+			break
+		}
+	}
+	return nil
+}
+
+// Insert adds an element to the store. Equal elements are allowed and keep FIFO ordering.
+// May flush the in-memory part to disk.
+func (s *Store[T]) Insert(element T) error {
+	s.live++
+	return s.insertMem(record[T]{val: element, tomb: false})
+}
+
+// Get returns the oldest element equal to the given one and true if found.
+func (s *Store[T]) Get(element T) (res T, found bool, err error) {
+	lives, err := s.equal(element)
+	if err != nil || len(lives) == 0 {
+		return res, false, err
+	}
+	return lives[0], true, nil
+}
+
+// Has returns true if an element equal to the given one exists in the store.
+func (s *Store[T]) Has(element T) (bool, error) {
+	_, found, err := s.Get(element)
+	return found, err
+}
+
+// ReplaceOrInsert inserts an element into the store, or replaces an existing equal element if found.
+// Returns the old element and true if an element was replaced.
+//
+// When multiple equal elements exist, the oldest one is replaced and keeps its place. Records on disk
+// can't be changed, so all k equal elements are cancelled with tombstones and written again,
+// which costs O(k) records.
+func (s *Store[T]) ReplaceOrInsert(element T) (old T, found bool, err error) {
+	lives, err := s.equal(element)
+	if err != nil {
+		return old, false, err
+	}
+	if len(lives) == 0 {
+		return old, false, s.Insert(element)
+	}
+	old = lives[0]
+	for i := range lives {
+		if err = s.insertMem(record[T]{val: lives[i], tomb: true}); err != nil {
+			return old, true, err
+		}
+	}
+	lives[0] = element
+	for i := range lives {
+		if err = s.insertMem(record[T]{val: lives[i], tomb: false}); err != nil {
+			return old, true, err
+		}
+	}
+	return old, true, nil
+}
+
+// Delete removes the oldest element equal to the given one and returns it along with true if found.
+// The deletion is recorded as a tombstone, which may flush the in-memory part to disk.
+func (s *Store[T]) Delete(element T) (deleted T, found bool, err error) {
+	if deleted, found, err = s.Get(element); !found || err != nil {
+		return deleted, found, err
+	}
+	s.live--
+	return deleted, true, s.insertMem(record[T]{val: deleted, tomb: true})
+}
+
+// Len returns the number of live elements in the store.
+func (s *Store[T]) Len() int {
+	return s.live
+}
+
+// Min returns the minimum element and true, or the zero value of T and false if the store is empty.
+// When multiple equal minimum elements exist, the oldest one is returned.
+func (s *Store[T]) Min() (minElem T, found bool, err error) {
+	return s.first(false)
+}
+
+// Max returns the maximum element and true, or the zero value of T and false if the store is empty.
+// When multiple equal maximum elements exist, the oldest one is returned.
+func (s *Store[T]) Max() (maxElem T, found bool, err error) {
+	return s.first(true)
+}
+
+// Ascend calls the iterator function for each element in ascending order.
+// Iteration stops early if the iterator returns false.
+func (s *Store[T]) Ascend(iterator bwarr.IteratorFunc[T]) error {
+	return s.AscendBounds(bwarr.Unbounded[T](), bwarr.Unbounded[T](), iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator function for each element that is greater than
+// or equal to the given one, in ascending order. Iteration stops early if the iterator returns false.
+func (s *Store[T]) AscendGreaterOrEqual(element T, iterator bwarr.IteratorFunc[T]) error {
+	return s.AscendBounds(bwarr.Included(element), bwarr.Unbounded[T](), iterator)
+}
+
+// AscendLessThan calls the iterator function for each element that is less than the given one,
+// in ascending order. Iteration stops early if the iterator returns false.
+func (s *Store[T]) AscendLessThan(element T, iterator bwarr.IteratorFunc[T]) error {
+	return s.AscendBounds(bwarr.Unbounded[T](), bwarr.Excluded(element), iterator)
+}
+
+// AscendRange calls the iterator function for each element that is greater than or equal to
+// greaterOrEqual and less than lessThan, in ascending order. Iteration stops early if the iterator returns false.
+func (s *Store[T]) AscendRange(greaterOrEqual, lessThan T, iterator bwarr.IteratorFunc[T]) error {
+	return s.AscendBounds(bwarr.Included(greaterOrEqual), bwarr.Excluded(lessThan), iterator)
+}
+
+// AscendBounds calls the iterator function for each element between lo and hi, in ascending order.
+// Iteration stops early if the iterator returns false.
+func (s *Store[T]) AscendBounds(lo, hi bwarr.Bound[T], iterator bwarr.IteratorFunc[T]) error {
+	srcs, err := s.sources(lo, false)
+	if err != nil {
+		return err
+	}
+	return s.walk(&merger[T]{srcs: srcs, cmp: s.cmp}, hi, iterator)
+}
+
+// Descend calls the iterator function for each element in descending order.
+// Equal elements come from the newest to the oldest, the reverse of Ascend.
+// Iteration stops early if the iterator returns false.
+func (s *Store[T]) Descend(iterator bwarr.IteratorFunc[T]) error {
+	return s.DescendBounds(bwarr.Unbounded[T](), bwarr.Unbounded[T](), iterator)
+}
+
+// DescendLessOrEqual calls the iterator function for each element that is less than or equal to
+// the given one, in descending order. Iteration stops early if the iterator returns false.
+func (s *Store[T]) DescendLessOrEqual(element T, iterator bwarr.IteratorFunc[T]) error {
+	return s.DescendBounds(bwarr.Unbounded[T](), bwarr.Included(element), iterator)
+}
+
+// DescendGreaterThan calls the iterator function for each element that is greater than the given one,
+// in descending order. Iteration stops early if the iterator returns false.
+func (s *Store[T]) DescendGreaterThan(element T, iterator bwarr.IteratorFunc[T]) error {
+	return s.DescendBounds(bwarr.Excluded(element), bwarr.Unbounded[T](), iterator)
+}
+
+// DescendRange calls the iterator function for each element that is greater than or equal to
+// greaterOrEqual and less than lessThan, in descending order. Iteration stops early if the iterator returns false.
+func (s *Store[T]) DescendRange(greaterOrEqual, lessThan T, iterator bwarr.IteratorFunc[T]) error {
+	return s.DescendBounds(bwarr.Included(greaterOrEqual), bwarr.Excluded(lessThan), iterator)
+}
+
+// DescendBounds calls the iterator function for each element between lo and hi, in descending order.
+// Equal elements come from the newest to the oldest. Iteration stops early if the iterator returns false.
+func (s *Store[T]) DescendBounds(lo, hi bwarr.Bound[T], iterator bwarr.IteratorFunc[T]) error {
+	srcs, err := s.sources(hi, true)
+	if err != nil {
+		return err
+	}
+	return s.walk(&merger[T]{srcs: srcs, cmp: s.cmp, desc: true}, lo, iterator)
+}
+
+// DeleteMin removes the minimum element and returns it along with true if the store is not empty.
+// When multiple equal minimum elements exist, the oldest one is removed.
+func (s *Store[T]) DeleteMin() (deleted T, found bool, err error) {
+	minElem, found, err := s.Min()
+	if !found || err != nil {
+		return deleted, false, err
+	}
+	return s.Delete(minElem)
+}
+
+// DeleteMax removes the maximum element and returns it along with true if the store is not empty.
+// When multiple equal maximum elements exist, the oldest one is removed.
+func (s *Store[T]) DeleteMax() (deleted T, found bool, err error) {
+	maxElem, found, err := s.Max()
+	if !found || err != nil {
+		return deleted, false, err
+	}
+	return s.Delete(maxElem)
+}
+
+// Clear removes all elements from the store and deletes its run files.
+func (s *Store[T]) Clear() error {
+	s.mem.Clear(false)
+	s.live = 0
+	var errs []error
+	for _, r := range s.runs {
+		if r != nil {
+			errs = append(errs, r.close(), os.Remove(r.path))
+		}
+	}
+	s.runs = s.runs[:0]
+	return errors.Join(errs...)
+}
+
+// Flush writes the in-memory part to disk, merging it with the runs of the lower ranks.
+func (s *Store[T]) Flush() error {
+	if s.mem.Len() == 0 {
+		return nil
+	}
+
+	dest := s.memRank
+	for dest < len(s.runs) && s.runs[dest] != nil {
+		dest++
+	}
+	bottom := true // No older runs: tombstones have nothing left to cancel.
+	for r := dest + 1; r < len(s.runs); r++ {
+		if s.runs[r] != nil {
+			bottom = false
+			break
+		}
+	}
+
+	srcs := make([]source[T], 0, dest-s.memRank+1)
+	for r := dest - 1; r >= s.memRank; r-- {
+		c, err := s.runs[r].cursor(0, s.codec)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, c)
+	}
+	srcs = append(srcs, s.memSource(bwarr.Unbounded[T](), false))
+
+	w, err := createRun(s.dir, dest, s.codec)
+	if err != nil {
+		return err
+	}
+	if err = s.mergeInto(w, srcs, bottom); err != nil {
+		return w.abort(err)
+	}
+	r, err := w.finish()
+	if err != nil {
+		return err
+	}
+
+	for rank := s.memRank; rank < dest; rank++ {
+		old := s.runs[rank]
+		s.runs[rank] = nil
+		if err = old.close(); err != nil {
+			return err
+		}
+		if err = os.Remove(old.path); err != nil {
+			return err
+		}
+	}
+	s.setRun(dest, r)
+	s.mem.Clear(false)
+	return nil
+}
+
+// Close flushes the in-memory part and closes the run files.
+func (s *Store[T]) Close() error {
+	err := s.Flush()
+	return errors.Join(err, s.closeRuns())
+}
+
+func (s *Store[T]) mergeInto(w *runWriter[T], srcs []source[T], bottom bool) error {
+	m := merger[T]{srcs: srcs, cmp: s.cmp}
+	var group []record[T]
+	var lives []T
+	for {
+		var err error
+		if group, err = m.nextGroup(group[:0]); err != nil {
+			return err
+		}
+		if len(group) == 0 {
+			return nil
+		}
+		if !bottom {
+			for i := range group {
+				if err = w.write(group[i]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		lives = resolveGroup(group, lives[:0])
+		for i := range lives {
+			if err = w.write(record[T]{val: lives[i], tomb: false}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// walk calls the iterator for the live elements of every group of the merge, until a group is beyond the bound
+// where the merge ends: the upper bound for an ascending merge, the lower bound for a descending one.
+func (s *Store[T]) walk(m *merger[T], end bwarr.Bound[T], iterator bwarr.IteratorFunc[T]) error {
+	endVal, bounded := end.Value()
+	var group []record[T]
+	var lives []T
+	for {
+		var err error
+		if group, err = m.nextGroup(group[:0]); err != nil {
+			return err
+		}
+		if len(group) == 0 {
+			return nil
+		}
+		if bounded {
+			c := s.cmp(group[0].val, endVal)
+			if m.desc {
+				c = -c
+			}
+			if c > 0 || (c == 0 && !end.IsIncluded()) {
+				return nil
+			}
+		}
+		lives = resolveGroup(group, lives[:0])
+		if m.desc {
+			slices.Reverse(lives)
+		}
+		for i := range lives {
+			if !iterator(lives[i]) {
+				return nil
+			}
+		}
+	}
+}
+
+// equal returns the live elements equal to the given one, from the oldest to the newest.
+func (s *Store[T]) equal(element T) ([]T, error) {
+	srcs, err := s.sources(bwarr.Included(element), false)
+	if err != nil {
+		return nil, err
+	}
+	m := merger[T]{srcs: srcs, cmp: s.cmp}
+	group, err := m.nextGroup(nil)
+	if err != nil || len(group) == 0 || s.cmp(group[0].val, element) != 0 {
+		return nil, err
+	}
+	return resolveGroup(group, nil), nil
+}
+
+// first returns the oldest of the minimum elements, or of the maximum ones if desc is set.
+func (s *Store[T]) first(desc bool) (res T, found bool, err error) {
+	srcs, err := s.sources(bwarr.Unbounded[T](), desc)
+	if err != nil {
+		return res, false, err
+	}
+	m := merger[T]{srcs: srcs, cmp: s.cmp, desc: desc}
+	var group []record[T]
+	var lives []T
+	for {
+		if group, err = m.nextGroup(group[:0]); err != nil || len(group) == 0 {
+			return res, false, err
+		}
+		if lives = resolveGroup(group, lives[:0]); len(lives) > 0 {
+			return lives[0], true, nil
+		}
+	}
+}
+
+// sources returns all sources from the oldest to the newest, positioned at the first record within
+// the bound from. If desc is set, the sources stream records in descending order and from is an upper bound.
+func (s *Store[T]) sources(from bwarr.Bound[T], desc bool) ([]source[T], error) {
+	srcs := make([]source[T], 0, len(s.runs)+1)
+	for rank := len(s.runs) - 1; rank >= 0; rank-- {
+		r := s.runs[rank]
+		if r == nil {
+			continue
+		}
+		var c source[T]
+		start, err := s.runStart(r, from, desc)
+		if err == nil && desc {
+			c, err = r.reverseCursor(start, s.codec)
+		} else if err == nil {
+			c, err = r.cursor(start, s.codec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, c)
+	}
+	return append(srcs, s.memSource(from, desc)), nil
+}
+
+// runStart returns the index of the first record of r within the lower bound from, or the index
+// after the last record within the upper bound from if desc is set.
+func (s *Store[T]) runStart(r *run[T], from bwarr.Bound[T], desc bool) (int, error) {
+	val, ok := from.Value()
+	switch {
+	case !ok && desc:
+		return r.count, nil
+	case !ok:
+		return 0, nil
+	}
+	// Records equal to val are skipped in ascending order if the bound excludes them,
+	// and are streamed in descending order if it includes them.
+	return r.search(val, from.IsIncluded() == desc, s.cmp, s.codec)
+}
+
+func (s *Store[T]) memSource(from bwarr.Bound[T], desc bool) *memSource[T] {
+	val, seek := from.Value()
+	// Sequence numbers start from 1, so an entry with sequence 0 precedes all entries equal to val
+	// and an entry with the maximum sequence follows them.
+	var seq uint64
+	if from.IsIncluded() == desc {
+		seq = math.MaxUint64
+	}
+	return &memSource[T]{ //nolint:exhaustruct
+		mem:  s.mem,
+		from: memEntry[T]{rec: record[T]{val: val, tomb: false}, seq: seq},
+		seek: seek,
+		desc: desc,
+	}
+}
+
+// insertMem adds the record to the in-memory part and flushes it to disk if it is full.
+func (s *Store[T]) insertMem(rec record[T]) error {
+	s.seq++
+	s.mem.Insert(memEntry[T]{rec: rec, seq: s.seq})
+	if s.mem.Len() < s.memCap {
+		return nil
+	}
+	return s.Flush()
+}
+
+func (s *Store[T]) setRun(rank int, r *run[T]) {
+	for len(s.runs) <= rank {
+		s.runs = append(s.runs, nil)
+	}
+	s.runs[rank] = r
+}
+
+func (s *Store[T]) closeRuns() error {
+	var errs []error
+	for _, r := range s.runs {
+		if r != nil {
+			errs = append(errs, r.close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func compareSeq(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
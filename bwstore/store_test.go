@@ -0,0 +1,426 @@
+package bwstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/dronnix/bwarr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	key int64
+	seq int64 // Not compared, used to check FIFO order of equal elements.
+}
+
+func itemCmp(a, b item) int {
+	return int(a.key - b.key)
+}
+
+type itemCodec struct{}
+
+func (itemCodec) Append(dst []byte, v item) []byte {
+	dst = binary.AppendVarint(dst, v.key)
+	return binary.AppendVarint(dst, v.seq)
+}
+
+func (itemCodec) Decode(src []byte) (v item, err error) {
+	var n, m int
+	v.key, n = binary.Varint(src)
+	if n <= 0 {
+		return v, errors.New("bad key")
+	}
+	v.seq, m = binary.Varint(src[n:])
+	if m <= 0 {
+		return v, errors.New("bad seq")
+	}
+	return v, nil
+}
+
+func openTestStore(t *testing.T, dir string, memElements int) *Store[item] {
+	t.Helper()
+	s, err := Open[item](dir, itemCmp, itemCodec{}, Options{MemElements: memElements})
+	require.NoError(t, err)
+	return s
+}
+
+func TestStore_InsertGetAscend(t *testing.T) {
+	t.Parallel()
+	s := openTestStore(t, t.TempDir(), 4)
+	defer s.Close()
+
+	const elemsNum = 100
+	want := make([]item, 0, elemsNum)
+	for i := range elemsNum {
+		it := item{key: int64(rand.Intn(elemsNum)), seq: int64(i)}
+		require.NoError(t, s.Insert(it))
+		want = append(want, it)
+	}
+	require.Equal(t, elemsNum, s.Len())
+	for _, it := range want {
+		got, found, err := s.Get(item{key: it.key})
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, it.key, got.key)
+	}
+	_, found, err := s.Get(item{key: elemsNum})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	slices.SortStableFunc(want, itemCmp)
+	assert.Equal(t, keys(want), keys(collect(t, s)))
+}
+
+func TestStore_FIFO(t *testing.T) {
+	t.Parallel()
+	s := openTestStore(t, t.TempDir(), 2)
+	defer s.Close()
+
+	const elemsNum = 37
+	for i := range elemsNum {
+		require.NoError(t, s.Insert(item{key: 42, seq: int64(i)}))
+	}
+	for i := range elemsNum {
+		got, found, err := s.Get(item{key: 42})
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, int64(i), got.seq)
+		deleted, found, err := s.Delete(item{key: 42})
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, int64(i), deleted.seq)
+	}
+	has, err := s.Has(item{key: 42})
+	require.NoError(t, err)
+	assert.False(t, has)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestStore_RandomOpsWithReopen(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s := openTestStore(t, dir, 8)
+
+	model := make([]item, 0)
+	r := rand.New(rand.NewSource(42))
+	for i := range 3000 {
+		key := int64(r.Intn(50))
+		switch op := r.Intn(12); {
+		case op < 6:
+			it := item{key: key, seq: int64(i)}
+			require.NoError(t, s.Insert(it))
+			model = append(model, it)
+		case op < 8:
+			deleteMax := op == 7
+			deleteExtreme := s.DeleteMin
+			if deleteMax {
+				deleteExtreme = s.DeleteMax
+			}
+			deleted, found, err := deleteExtreme()
+			require.NoError(t, err)
+			require.Equal(t, len(model) > 0, found)
+			if found {
+				idx := oldestExtreme(model, deleteMax)
+				require.Equal(t, model[idx], deleted)
+				model = slices.Delete(model, idx, idx+1)
+			}
+		case op < 11:
+			deleted, found, err := s.Delete(item{key: key})
+			require.NoError(t, err)
+			idx := slices.IndexFunc(model, func(it item) bool { return it.key == key })
+			require.Equal(t, idx >= 0, found)
+			if found {
+				require.Equal(t, model[idx], deleted)
+				model = slices.Delete(model, idx, idx+1)
+			}
+		default:
+			require.NoError(t, s.Close())
+			s = openTestStore(t, dir, 8)
+		}
+		require.Equal(t, len(model), s.Len())
+	}
+
+	sorted := slices.Clone(model)
+	slices.SortStableFunc(sorted, itemCmp)
+	assert.Equal(t, sorted, collect(t, s))
+	slices.Reverse(sorted)
+	assert.Equal(t, sorted, collectDesc(t, s))
+	slices.Reverse(sorted)
+
+	var got []int64
+	require.NoError(t, s.AscendGreaterOrEqual(item{key: 25}, func(it item) bool {
+		got = append(got, it.key)
+		return len(got) < 10
+	}))
+	idx := slices.IndexFunc(sorted, func(it item) bool { return it.key >= 25 })
+	assert.Equal(t, keys(sorted[idx:min(idx+10, len(sorted))]), got)
+	require.NoError(t, s.Close())
+}
+
+func TestStore_ReplaceMinMaxRanges(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s := openTestStore(t, dir, 4)
+	defer s.Close()
+
+	model := make([]item, 0) // In insertion order, replaced elements keep their place.
+	r := rand.New(rand.NewSource(7))
+	for i := range 1500 {
+		it := item{key: int64(r.Intn(30)), seq: int64(i)}
+		switch op := r.Intn(4); {
+		case op < 2:
+			require.NoError(t, s.Insert(it))
+			model = append(model, it)
+		case op < 3:
+			old, found, err := s.ReplaceOrInsert(it)
+			require.NoError(t, err)
+			idx := slices.IndexFunc(model, func(m item) bool { return m.key == it.key })
+			require.Equal(t, idx >= 0, found)
+			if found {
+				require.Equal(t, model[idx], old)
+				model[idx] = it
+			} else {
+				model = append(model, it)
+			}
+		default:
+			_, _, err := s.Delete(it)
+			require.NoError(t, err)
+			if idx := slices.IndexFunc(model, func(m item) bool { return m.key == it.key }); idx >= 0 {
+				model = slices.Delete(model, idx, idx+1)
+			}
+		}
+		require.Equal(t, len(model), s.Len())
+	}
+
+	sorted := slices.Clone(model)
+	slices.SortStableFunc(sorted, itemCmp)
+	assert.Equal(t, sorted, collect(t, s))
+	minElem, found, err := s.Min()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, model[oldestExtreme(model, false)], minElem)
+	maxElem, found, err := s.Max()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, model[oldestExtreme(model, true)], maxElem)
+
+	between := func(lo, hi int64) []item {
+		var res []item
+		for _, it := range sorted {
+			if it.key >= lo && it.key < hi {
+				res = append(res, it)
+			}
+		}
+		return res
+	}
+	reversed := func(items []item) []item {
+		items = slices.Clone(items)
+		slices.Reverse(items)
+		return items
+	}
+	for _, b := range [][2]int64{{0, 30}, {5, 12}, {12, 5}, {-1, 0}, {29, 31}, {7, 8}} {
+		lo, hi := item{key: b[0]}, item{key: b[1]}
+		assert.Equal(t, between(b[0], b[1]),
+			collectWith(t, func(fn bwarr.IteratorFunc[item]) error { return s.AscendRange(lo, hi, fn) }))
+		assert.Equal(t, between(-1, b[1]),
+			collectWith(t, func(fn bwarr.IteratorFunc[item]) error { return s.AscendLessThan(hi, fn) }))
+		assert.Equal(t, reversed(between(b[0], b[1])),
+			collectWith(t, func(fn bwarr.IteratorFunc[item]) error { return s.DescendRange(lo, hi, fn) }))
+		assert.Equal(t, reversed(between(-1, b[1]+1)),
+			collectWith(t, func(fn bwarr.IteratorFunc[item]) error { return s.DescendLessOrEqual(hi, fn) }))
+		assert.Equal(t, reversed(between(b[0]+1, 31)),
+			collectWith(t, func(fn bwarr.IteratorFunc[item]) error { return s.DescendGreaterThan(lo, fn) }))
+	}
+
+	require.NoError(t, s.Clear())
+	assert.Equal(t, 0, s.Len())
+	assert.Empty(t, collect(t, s))
+	_, found, err = s.Max()
+	require.NoError(t, err)
+	assert.False(t, found)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	require.NoError(t, s.Insert(item{key: 3, seq: 1}))
+	assert.Equal(t, []item{{key: 3, seq: 1}}, collect(t, s))
+}
+
+func TestStore_TombstonesDroppedAtBottom(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s := openTestStore(t, dir, 2)
+	for i := range 8 {
+		require.NoError(t, s.Insert(item{key: int64(i), seq: 0}))
+	}
+	for i := range 8 {
+		_, found, err := s.Delete(item{key: int64(i)})
+		require.NoError(t, err)
+		require.True(t, found)
+	}
+	require.NoError(t, s.Close())
+
+	s = openTestStore(t, dir, 2)
+	defer s.Close()
+	assert.Equal(t, 0, s.Len())
+	records := 0
+	for _, r := range s.runs {
+		if r != nil {
+			records += r.count
+		}
+	}
+	assert.Less(t, records, 16, "some tombstones should be dropped with cancelled elements")
+	assert.Empty(t, collect(t, s))
+}
+
+func TestStore_RemovesInterruptedMerge(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/"+runFileName(3)+runTmpExt, []byte("garbage"), 0o600))
+	s := openTestStore(t, dir, 4)
+	defer s.Close()
+	_, err := os.Stat(dir + "/" + runFileName(3) + runTmpExt)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStore_LargeEqualGroupInMemory(t *testing.T) {
+	t.Parallel()
+	s := openTestStore(t, t.TempDir(), 1024)
+	defer s.Close()
+
+	const elemsNum = 5*memBatch + 3 // Spans several batches of the in-memory source.
+	want := make([]item, 0, elemsNum+2)
+	for i := range elemsNum {
+		require.NoError(t, s.Insert(item{key: 7, seq: int64(i)}))
+		want = append(want, item{key: 7, seq: int64(i)})
+	}
+	require.NoError(t, s.Insert(item{key: 9, seq: elemsNum}))
+	require.NoError(t, s.Insert(item{key: 5, seq: elemsNum + 1}))
+	want = append([]item{{key: 5, seq: elemsNum + 1}}, append(want, item{key: 9, seq: elemsNum})...)
+	assert.Equal(t, want, collect(t, s))
+	slices.Reverse(want)
+	assert.Equal(t, want, collectDesc(t, s))
+
+	for i := range elemsNum {
+		deleted, found, err := s.Delete(item{key: 7})
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, int64(i), deleted.seq)
+	}
+	assert.Equal(t, []item{{key: 5, seq: elemsNum + 1}, {key: 9, seq: elemsNum}}, collect(t, s))
+}
+
+func TestStore_CorruptedRecordLength(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s := openTestStore(t, dir, 2)
+	for i := range 4 {
+		require.NoError(t, s.Insert(item{key: int64(i), seq: 0}))
+	}
+	require.NoError(t, s.Close())
+
+	path := dir + "/" + runFileName(2)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// The first record starts at offset 0: replace its one-byte payload length with a huge one,
+	// and move the offset table position in the footer accordingly.
+	huge := binary.AppendUvarint(nil, 1<<60)
+	data = append(append([]byte{recordLive}, huge...), data[2:]...)
+	tableOff := data[len(data)-runFooterSize+24:]
+	binary.LittleEndian.PutUint64(tableOff, binary.LittleEndian.Uint64(tableOff)+uint64(len(huge)-1))
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	s = openTestStore(t, dir, 2)
+	defer s.Close()
+	_, _, err = s.Get(item{key: 0})
+	require.ErrorIs(t, err, errCorruptedRun)
+	require.ErrorIs(t, s.Ascend(func(item) bool { return true }), errCorruptedRun)
+	require.ErrorIs(t, s.Descend(func(item) bool { return true }), errCorruptedRun)
+}
+
+type itemPtrCodec struct{ itemCodec }
+
+func (c itemPtrCodec) Append(dst []byte, v *item) []byte {
+	return c.itemCodec.Append(dst, *v)
+}
+
+func (c itemPtrCodec) Decode(src []byte) (*item, error) {
+	v, err := c.itemCodec.Decode(src)
+	return &v, err
+}
+
+func TestStore_PointerElements(t *testing.T) {
+	t.Parallel()
+	// The comparator dereferences its arguments, so the merge must never compare against a zero element.
+	ptrCmp := func(a, b *item) int { return itemCmp(*a, *b) }
+	s, err := Open[*item](t.TempDir(), ptrCmp, itemPtrCodec{}, Options{MemElements: 2})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := range 7 {
+		require.NoError(t, s.Insert(&item{key: int64(i % 3), seq: int64(i)}))
+	}
+	got, found, err := s.Get(&item{key: 1})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, item{key: 1, seq: 1}, *got)
+
+	var res []item
+	require.NoError(t, s.Ascend(func(it *item) bool {
+		res = append(res, *it)
+		return true
+	}))
+	assert.Equal(t, []item{{0, 0}, {0, 3}, {0, 6}, {1, 1}, {1, 4}, {2, 2}, {2, 5}}, res)
+	deleted, found, err := s.DeleteMax()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, item{key: 2, seq: 2}, *deleted)
+}
+
+// oldestExtreme returns the index of the oldest minimum (or maximum) element of items in insertion order.
+func oldestExtreme(items []item, maxElem bool) int {
+	idx := 0
+	for i := range items {
+		if c := itemCmp(items[i], items[idx]); (c < 0 && !maxElem) || (c > 0 && maxElem) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+func collectDesc(t *testing.T, s *Store[item]) []item {
+	t.Helper()
+	var res []item
+	require.NoError(t, s.Descend(func(it item) bool {
+		res = append(res, it)
+		return true
+	}))
+	return res
+}
+
+func collect(t *testing.T, s *Store[item]) []item {
+	t.Helper()
+	return collectWith(t, s.Ascend)
+}
+
+func collectWith(t *testing.T, walk func(bwarr.IteratorFunc[item]) error) []item {
+	t.Helper()
+	var res []item
+	require.NoError(t, walk(func(it item) bool {
+		res = append(res, it)
+		return true
+	}))
+	return res
+}
+
+func keys(items []item) []int64 {
+	res := make([]int64, len(items))
+	for i := range items {
+		res[i] = items[i].key
+	}
+	return res
+}
@@ -0,0 +1,116 @@
+package bwwal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot file layout (all integers are little-endian):
+//
+//	header:   magic | generation (uint64) | number of elements (uint64)
+//	elements: [uvarint payload length][payload] ...
+//	footer:   CRC32-C of everything above (uint32)
+const snapshotMagic = "BWSN"
+
+func writeSnapshot[T any](dir string, gen uint64, elems []T, codec Codec[T]) error {
+	tmpPath := filepath.Join(dir, snapshotFileName+tmpExt)
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	crc := crc32.New(crcTable)
+	w := bufio.NewWriter(io.MultiWriter(f, crc))
+	if err = writeSnapshotBody(w, gen, elems, codec); err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = binary.Write(f, binary.LittleEndian, crc.Sum32())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, filepath.Join(dir, snapshotFileName)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func writeSnapshotBody[T any](w *bufio.Writer, gen uint64, elems []T, codec Codec[T]) error {
+	var head [len(snapshotMagic) + 16]byte
+	copy(head[:], snapshotMagic)
+	binary.LittleEndian.PutUint64(head[4:], gen)
+	binary.LittleEndian.PutUint64(head[12:], uint64(len(elems)))
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	var buf []byte
+	for i := range elems {
+		buf = codec.Append(buf[:0], elems[i])
+		var size [binary.MaxVarintLen64]byte
+		if _, err := w.Write(size[:binary.PutUvarint(size[:], uint64(len(buf)))]); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSnapshot returns the elements and the generation of the snapshot, or nothing and zero
+// generation if there is no snapshot yet.
+func readSnapshot[T any](path string, codec Codec[T]) (elems []T, gen uint64, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	const headSize = len(snapshotMagic) + 16
+	if len(data) < headSize+4 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, 0, fmt.Errorf("%w: bad snapshot header", ErrCorrupted)
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, crcTable) != sum {
+		return nil, 0, fmt.Errorf("%w: bad snapshot checksum", ErrCorrupted)
+	}
+	gen = binary.LittleEndian.Uint64(body[4:])
+	count := binary.LittleEndian.Uint64(body[12:])
+	body = body[headSize:]
+	elems = make([]T, 0, min(count, uint64(len(body))))
+	for len(body) > 0 {
+		size, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < size {
+			return nil, 0, fmt.Errorf("%w: bad snapshot element", ErrCorrupted)
+		}
+		elem, err := codec.Decode(body[n : n+int(size)]) //nolint:gosec
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", ErrCorrupted, err)
+		}
+		elems = append(elems, elem)
+		body = body[n+int(size):] //nolint:gosec
+	}
+	if uint64(len(elems)) != count {
+		return nil, 0, fmt.Errorf("%w: snapshot has %d elements, expected %d", ErrCorrupted, len(elems), count)
+	}
+	return elems, gen, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(d.Sync(), d.Close())
+}
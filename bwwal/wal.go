@@ -0,0 +1,363 @@
+// Package bwwal adds crash safety to BWArr with a write-ahead log.
+//
+// Every mutation is appended to a log file as a length-prefixed, checksummed record
+// before it is applied to the in-memory BWArr; the checksum covers the length as well.
+// On open, the last snapshot is loaded and the log is replayed on top of it. A torn record
+// at the end of the log (a crash in the middle of a write) is discarded. Snapshot writes
+// the full content of the BWArr and truncates the log. Both files carry a generation number, so a log left over by a crash
+// between writing a snapshot and truncating the log is not replayed twice.
+//
+// The snapshot keeps the FIFO order of equal elements, and every BWArr operation picks
+// among equal elements by their age only, so replaying the same operations on top of it
+// restores the same state.
+package bwwal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dronnix/bwarr"
+)
+
+const (
+	logFileName      = "wal.log"
+	snapshotFileName = "snapshot.bin"
+	tmpExt           = ".tmp"
+
+	logHeaderSize    = 8 // Generation of the snapshot the log applies to.
+	recordHeaderSize = 8 // Payload length and CRC32-C of the length and the payload, uint32 each.
+	defaultGroupSize = 64
+)
+
+const (
+	opInsert byte = iota + 1
+	opDelete
+	opReplaceOrInsert
+	opDeleteMin
+	opDeleteMax
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCorrupted is returned when the snapshot or a record in the middle of the log is damaged.
+var ErrCorrupted = errors.New("bwwal: corrupted data")
+
+// SyncPolicy defines when the log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways writes and fsyncs the log after every mutation.
+	SyncAlways SyncPolicy = iota
+	// SyncGroup buffers up to Options.GroupSize mutations and commits them with a single
+	// write and fsync. Buffered mutations are lost on crash unless Sync is called.
+	SyncGroup
+	// SyncNever writes every mutation to the operating system, but never fsyncs the log
+	// except on Sync, Snapshot and Close. Survives process crashes, but not power loss.
+	SyncNever
+)
+
+// Codec converts elements to and from the binary representation stored in the log and the snapshot.
+// Its method set matches bwstore.Codec, so one codec can serve both packages.
+type Codec[T any] interface {
+	// Append appends the binary representation of v to dst and returns the extended buffer.
+	Append(dst []byte, v T) []byte
+	// Decode restores an element from the bytes produced by Append. It must not retain src.
+	Decode(src []byte) (T, error)
+}
+
+// Options configures a Log.
+type Options struct {
+	Sync SyncPolicy
+	// Number of mutations committed together with SyncGroup policy. Zero means 64.
+	GroupSize int
+	// Capacity hint for the BWArr, see bwarr.New.
+	Capacity int
+}
+
+// Log is a BWArr whose mutations are recorded in a write-ahead log.
+// Log is not safe for concurrent use.
+type Log[T any] struct {
+	dir     string
+	codec   Codec[T]
+	opts    Options
+	bwa     *bwarr.BWArr[T]
+	f       *os.File
+	w       *bufio.Writer
+	gen     uint64 // Generation of the last snapshot.
+	pending int    // Mutations written since the last commit.
+	buf     []byte
+}
+
+// Open opens the log located in dir, creating the directory if needed, and restores
+// the BWArr from the last snapshot and the log.
+func Open[T any](dir string, cmp bwarr.CmpFunc[T], codec Codec[T], opts Options) (*Log[T], error) {
+	if opts.GroupSize <= 0 {
+		opts.GroupSize = defaultGroupSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:mnd
+		return nil, err
+	}
+	l := &Log[T]{dir: dir, codec: codec, opts: opts} //nolint:exhaustruct
+
+	elems, gen, err := readSnapshot(filepath.Join(dir, snapshotFileName), codec)
+	if err != nil {
+		return nil, err
+	}
+	l.gen = gen
+	l.bwa = bwarr.New(cmp, max(opts.Capacity, len(elems)))
+	for i := range elems { // Equal elements are stored from the oldest to the newest.
+		l.bwa.Insert(elems[i])
+	}
+
+	if l.f, err = os.OpenFile(filepath.Join(dir, logFileName), os.O_RDWR|os.O_CREATE, 0o644); err != nil { //nolint:mnd
+		return nil, err
+	}
+	if err = l.replay(); err != nil {
+		l.f.Close()
+		return nil, err
+	}
+	l.w = bufio.NewWriter(l.f)
+	return l, nil
+}
+
+// BWArr returns the underlying BWArr for read operations. It must not be modified directly,
+// otherwise the log diverges from it.
+func (l *Log[T]) BWArr() *bwarr.BWArr[T] {
+	return l.bwa
+}
+
+// Insert logs and applies BWArr.Insert.
+func (l *Log[T]) Insert(element T) error {
+	if err := l.log(opInsert, element); err != nil {
+		return err
+	}
+	l.bwa.Insert(element)
+	return nil
+}
+
+// Delete logs and applies BWArr.Delete.
+func (l *Log[T]) Delete(element T) (deleted T, found bool, err error) {
+	if err = l.log(opDelete, element); err != nil {
+		return deleted, false, err
+	}
+	deleted, found = l.bwa.Delete(element)
+	return deleted, found, nil
+}
+
+// ReplaceOrInsert logs and applies BWArr.ReplaceOrInsert.
+func (l *Log[T]) ReplaceOrInsert(element T) (old T, replaced bool, err error) {
+	if err = l.log(opReplaceOrInsert, element); err != nil {
+		return old, false, err
+	}
+	old, replaced = l.bwa.ReplaceOrInsert(element)
+	return old, replaced, nil
+}
+
+// DeleteMin logs and applies BWArr.DeleteMin. Nothing is logged if the BWArr is empty.
+func (l *Log[T]) DeleteMin() (deleted T, found bool, err error) {
+	if l.bwa.Len() == 0 {
+		return deleted, false, nil
+	}
+	if err = l.log(opDeleteMin, deleted); err != nil {
+		return deleted, false, err
+	}
+	deleted, found = l.bwa.DeleteMin()
+	return deleted, found, nil
+}
+
+// DeleteMax logs and applies BWArr.DeleteMax. Nothing is logged if the BWArr is empty.
+func (l *Log[T]) DeleteMax() (deleted T, found bool, err error) {
+	if l.bwa.Len() == 0 {
+		return deleted, false, nil
+	}
+	if err = l.log(opDeleteMax, deleted); err != nil {
+		return deleted, false, err
+	}
+	deleted, found = l.bwa.DeleteMax()
+	return deleted, found, nil
+}
+
+// Sync writes all buffered mutations to the log and fsyncs it.
+func (l *Log[T]) Sync() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	l.pending = 0
+	return l.f.Sync()
+}
+
+// Snapshot atomically writes the full content of the BWArr to the snapshot file and truncates the log.
+// Elements are written in ascending order, equal ones from the oldest to the newest, so Open restores
+// their FIFO order.
+func (l *Log[T]) Snapshot() error {
+	var first bwarr.Token[T]
+	elems, _ := l.bwa.Page(first, l.bwa.Len()) // Unlike Ascend, Page returns equal elements in FIFO order.
+	if err := writeSnapshot(l.dir, l.gen+1, elems, l.codec); err != nil {
+		return err
+	}
+	l.gen++
+	l.w.Reset(l.f) // Buffered mutations are already in the snapshot.
+	if err := l.resetLog(); err != nil {
+		return err
+	}
+	l.pending = 0
+	return nil
+}
+
+// Close syncs and closes the log.
+func (l *Log[T]) Close() error {
+	return errors.Join(l.Sync(), l.f.Close())
+}
+
+// log appends the mutation to the log and commits it. Callers apply the mutation afterwards,
+// so the BWArr never has a mutation the log failed to record.
+func (l *Log[T]) log(op byte, element T) error {
+	if err := l.append(op, element); err != nil {
+		return err
+	}
+	return l.commit()
+}
+
+func (l *Log[T]) append(op byte, element T) error {
+	l.buf = append(l.buf[:0], make([]byte, recordHeaderSize)...)
+	l.buf = append(l.buf, op)
+	if op != opDeleteMin && op != opDeleteMax {
+		l.buf = l.codec.Append(l.buf, element)
+	}
+	payload := l.buf[recordHeaderSize:]
+	binary.LittleEndian.PutUint32(l.buf[0:], uint32(len(payload))) //nolint:gosec
+	binary.LittleEndian.PutUint32(l.buf[4:], recordChecksum(l.buf[:4], payload))
+	_, err := l.w.Write(l.buf)
+	return err
+}
+
+func (l *Log[T]) commit() error {
+	l.pending++
+	switch l.opts.Sync {
+	case SyncAlways:
+		return l.Sync()
+	case SyncGroup:
+		if l.pending >= l.opts.GroupSize {
+			return l.Sync()
+		}
+		return nil
+	default:
+		l.pending = 0
+		return l.w.Flush()
+	}
+}
+
+// resetLog truncates the log and writes the header of the current generation.
+func (l *Log[T]) resetLog() error {
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	var head [logHeaderSize]byte
+	binary.LittleEndian.PutUint64(head[:], l.gen)
+	if _, err := l.f.WriteAt(head[:], 0); err != nil {
+		return err
+	}
+	if _, err := l.f.Seek(logHeaderSize, io.SeekStart); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+// replay applies all valid records of the log and cuts off a torn record at the end.
+// A log of an older generation is already included in the snapshot and is discarded.
+func (l *Log[T]) replay() error {
+	r := bufio.NewReader(l.f)
+	var logHead [logHeaderSize]byte
+	if _, err := io.ReadFull(r, logHead[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return l.resetLog()
+		}
+		return err
+	}
+	switch gen := binary.LittleEndian.Uint64(logHead[:]); {
+	case gen < l.gen:
+		return l.resetLog()
+	case gen > l.gen:
+		return fmt.Errorf("%w: log generation %d is newer than snapshot generation %d", ErrCorrupted, gen, l.gen)
+	}
+
+	st, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	valid := int64(logHeaderSize) // Offset after the last valid record.
+	var head [recordHeaderSize]byte
+	var payload []byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return err
+		}
+		size := binary.LittleEndian.Uint32(head[0:])
+		if int64(size) > st.Size()-valid-recordHeaderSize { // A torn or damaged length, do not allocate it.
+			break
+		}
+		payload = append(payload[:0], make([]byte, size)...)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return err
+		}
+		if size == 0 || recordChecksum(head[:4], payload) != binary.LittleEndian.Uint32(head[4:]) {
+			if _, err := r.Peek(1); err == nil { // Damaged record is not the last one.
+				return fmt.Errorf("%w: bad record at offset %d", ErrCorrupted, valid)
+			}
+			break
+		}
+		if err := l.apply(payload); err != nil {
+			return err
+		}
+		valid += recordHeaderSize + int64(size)
+	}
+	if err := l.f.Truncate(valid); err != nil {
+		return err
+	}
+	_, err = l.f.Seek(valid, io.SeekStart)
+	return err
+}
+
+// recordChecksum returns CRC32-C of the encoded payload length followed by the payload.
+func recordChecksum(size, payload []byte) uint32 {
+	return crc32.Update(crc32.Checksum(size, crcTable), crcTable, payload)
+}
+
+func (l *Log[T]) apply(payload []byte) error {
+	op := payload[0]
+	if op == opDeleteMin {
+		l.bwa.DeleteMin()
+		return nil
+	}
+	if op == opDeleteMax {
+		l.bwa.DeleteMax()
+		return nil
+	}
+	element, err := l.codec.Decode(payload[1:])
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	switch op {
+	case opInsert:
+		l.bwa.Insert(element)
+	case opDelete:
+		l.bwa.Delete(element)
+	case opReplaceOrInsert:
+		l.bwa.ReplaceOrInsert(element)
+	default:
+		return fmt.Errorf("%w: unknown operation %d", ErrCorrupted, op)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package bwwal
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dronnix/bwarr"
+)
+
+type item struct {
+	key int64
+	seq int64 // Not compared, used to check FIFO order of equal elements.
+}
+
+func itemCmp(a, b item) int {
+	return int(a.key - b.key)
+}
+
+type itemCodec struct{}
+
+func (itemCodec) Append(dst []byte, v item) []byte {
+	dst = binary.AppendVarint(dst, v.key)
+	return binary.AppendVarint(dst, v.seq)
+}
+
+func (itemCodec) Decode(src []byte) (v item, err error) {
+	var n, m int
+	v.key, n = binary.Varint(src)
+	if n <= 0 {
+		return v, errors.New("bad key")
+	}
+	v.seq, m = binary.Varint(src[n:])
+	if m <= 0 {
+		return v, errors.New("bad seq")
+	}
+	return v, nil
+}
+
+func openTestLog(t *testing.T, dir string, opts Options) *Log[item] {
+	t.Helper()
+	l, err := Open[item](dir, itemCmp, itemCodec{}, opts)
+	require.NoError(t, err)
+	return l
+}
+
+func TestLog_ReplayRestoresState(t *testing.T) {
+	t.Parallel()
+	for _, policy := range []SyncPolicy{SyncAlways, SyncGroup, SyncNever} {
+		dir := t.TempDir()
+		l := openTestLog(t, dir, Options{Sync: policy, GroupSize: 7})
+		r := rand.New(rand.NewSource(int64(policy)))
+		for i := range 2000 {
+			key := int64(r.Intn(20))
+			var err error
+			switch r.Intn(10) {
+			case 0:
+				_, _, err = l.DeleteMin()
+			case 1:
+				_, _, err = l.DeleteMax()
+			case 2, 3:
+				_, _, err = l.Delete(item{key: key})
+			case 4:
+				_, _, err = l.ReplaceOrInsert(item{key: key, seq: int64(i)})
+			case 5:
+				if i%100 == 5 {
+					err = l.Snapshot()
+				}
+			default:
+				err = l.Insert(item{key: key, seq: int64(i)})
+			}
+			require.NoError(t, err)
+		}
+		want := dump(l.BWArr())
+		require.NoError(t, l.Close())
+
+		l = openTestLog(t, dir, Options{Sync: policy})
+		assert.Equal(t, want, dump(l.BWArr()), "policy %d", policy)
+		require.NoError(t, l.Close())
+	}
+}
+
+func TestLog_SnapshotKeepsFIFO(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := openTestLog(t, dir, Options{Sync: SyncNever})
+	r := rand.New(rand.NewSource(27))
+	want := make([]item, 0, 1000)
+	for i := range 1000 {
+		it := item{key: int64(r.Intn(10)), seq: int64(i)}
+		require.NoError(t, l.Insert(it))
+		want = append(want, it)
+	}
+	slices.SortStableFunc(want, itemCmp)
+	require.NoError(t, l.Snapshot())
+	assert.Equal(t, want, dump(l.BWArr()))
+
+	for _, w := range want[:500] {
+		got, found, err := l.DeleteMin()
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, w, got)
+	}
+	require.NoError(t, l.Snapshot())
+	require.NoError(t, l.Close())
+
+	l = openTestLog(t, dir, Options{Sync: SyncNever})
+	defer l.Close()
+	assert.Equal(t, want[500:], dump(l.BWArr()))
+	for _, w := range want[500:] {
+		got, found, err := l.DeleteMin()
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, w, got)
+	}
+}
+
+func TestLog_DamagedLength(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := openTestLog(t, dir, Options{Sync: SyncAlways})
+	for i := range 10 {
+		require.NoError(t, l.Insert(item{key: int64(i), seq: int64(i)}))
+	}
+	require.NoError(t, l.Close())
+
+	path := filepath.Join(dir, logFileName)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	first := data[logHeaderSize : logHeaderSize+recordHeaderSize]
+	binary.LittleEndian.PutUint32(first, binary.LittleEndian.Uint32(first)+1) // Still fits in the file.
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	_, err = Open[item](dir, itemCmp, itemCodec{}, Options{}) //nolint:exhaustruct
+	require.ErrorIs(t, err, ErrCorrupted, "the checksum must cover the length")
+
+	binary.LittleEndian.PutUint32(first, 1<<31) // Longer than the file: a torn record, nothing is allocated.
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	l = openTestLog(t, dir, Options{Sync: SyncAlways})
+	defer l.Close()
+	assert.Equal(t, 0, l.BWArr().Len())
+}
+
+func TestLog_TornTailIsDiscarded(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := openTestLog(t, dir, Options{Sync: SyncAlways})
+	for i := range 10 {
+		require.NoError(t, l.Insert(item{key: int64(i), seq: int64(i)}))
+	}
+	require.NoError(t, l.Close())
+
+	f, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{42, 0, 0, 0, 1, 2}) // Header of a record that was never completed.
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l = openTestLog(t, dir, Options{Sync: SyncAlways})
+	assert.Equal(t, 10, l.BWArr().Len())
+	require.NoError(t, l.Insert(item{key: 100, seq: 100}))
+	require.NoError(t, l.Close())
+
+	l = openTestLog(t, dir, Options{Sync: SyncAlways})
+	defer l.Close()
+	assert.Equal(t, 11, l.BWArr().Len())
+}
+
+func TestLog_CorruptedRecordInTheMiddle(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := openTestLog(t, dir, Options{Sync: SyncAlways})
+	for i := range 10 {
+		require.NoError(t, l.Insert(item{key: int64(i), seq: int64(i)}))
+	}
+	require.NoError(t, l.Close())
+
+	path := filepath.Join(dir, logFileName)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[logHeaderSize+recordHeaderSize] ^= 0xFF // Damage the payload of the first record.
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = Open[item](dir, itemCmp, itemCodec{}, Options{}) //nolint:exhaustruct
+	require.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestLog_StaleLogAfterSnapshotIsNotReplayed(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := openTestLog(t, dir, Options{Sync: SyncAlways})
+	for i := range 10 {
+		require.NoError(t, l.Insert(item{key: int64(i), seq: int64(i)}))
+	}
+	path := filepath.Join(dir, logFileName)
+	staleLog, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Snapshot())
+	require.NoError(t, l.Close())
+
+	// Simulate a crash between writing the snapshot and truncating the log.
+	require.NoError(t, os.WriteFile(path, staleLog, 0o600))
+	l = openTestLog(t, dir, Options{Sync: SyncAlways})
+	defer l.Close()
+	assert.Equal(t, 10, l.BWArr().Len())
+}
+
+func TestLog_GroupCommit(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l := openTestLog(t, dir, Options{Sync: SyncGroup, GroupSize: 4})
+	defer l.Close()
+	path := filepath.Join(dir, logFileName)
+	logSize := func() int64 {
+		st, err := os.Stat(path)
+		require.NoError(t, err)
+		return st.Size()
+	}
+
+	for i := range 3 {
+		require.NoError(t, l.Insert(item{key: int64(i), seq: int64(i)}))
+	}
+	assert.Equal(t, int64(logHeaderSize), logSize(), "mutations should wait for the group")
+	require.NoError(t, l.Insert(item{key: 3, seq: 3}))
+	assert.Greater(t, logSize(), int64(logHeaderSize), "full group should be committed")
+}
+
+// dump returns the elements in ascending order, equal ones from the oldest to the newest.
+func dump(bwa *bwarr.BWArr[item]) []item {
+	var first bwarr.Token[item]
+	res, _ := bwa.Page(first, bwa.Len())
+	return res
+}
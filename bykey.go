@@ -0,0 +1,121 @@
+package bwarr
+
+import (
+	"cmp"
+)
+
+// ByKey is a BWArr of elements ordered by a key extracted from them. In addition to the BWArr methods,
+// elements can be looked up by key, without building a T to pass to Get or Delete.
+type ByKey[T any, K cmp.Ordered] struct {
+	*BWArr[T]
+	key func(T) K
+}
+
+// NewByKey creates a new empty ByKey ordered by the keys returned by key, as by cmp.Compare.
+// The key function should be cheap, it is called on every comparison.
+func NewByKey[T any, K cmp.Ordered](key func(T) K) *ByKey[T, K] {
+	return &ByKey[T, K]{
+		BWArr: New(func(a, b T) int { return cmp.Compare(key(a), key(b)) }, 0),
+		key:   key,
+	}
+}
+
+// Clone creates a deep copy of the ByKey, see BWArr.Clone.
+func (bk *ByKey[T, K]) Clone() *ByKey[T, K] {
+	return &ByKey[T, K]{BWArr: bk.BWArr.Clone(), key: bk.key}
+}
+
+// GetByKey returns the element with key k and true if found, or the zero value of T and false if not found.
+// When multiple elements have the key, the first inserted element is returned.
+func (bk *ByKey[T, K]) GetByKey(k K) (res T, found bool) {
+	if segNum, index := bk.searchKey(k); index >= 0 {
+		return bk.whiteSegments[segNum].elements[index], true
+	}
+	return
+}
+
+// HasKey returns true if an element with key k exists in the ByKey.
+func (bk *ByKey[T, K]) HasKey(k K) bool {
+	_, index := bk.searchKey(k)
+	return index >= 0
+}
+
+// DeleteKey removes the element with key k and returns it along with true if found, or the zero value of T
+// and false if not found. When multiple elements have the key, the first inserted element is deleted.
+func (bk *ByKey[T, K]) DeleteKey(k K) (deleted T, found bool) {
+	segNum, index := bk.searchKey(k)
+	if segNum < 0 {
+		return deleted, false
+	}
+	return bk.del(segNum, index), true
+}
+
+// AscendRangeKeys calls the iterator function for each element with key greater than or equal to from
+// and less than to, in ascending order. Iteration stops early if the iterator returns false.
+func (bk *ByKey[T, K]) AscendRangeKeys(from, to K, iterator IteratorFunc[T]) {
+	bk.AscendKeyBounds(Included(from), Excluded(to), iterator)
+}
+
+// AscendKeyBounds calls the iterator function for each element with key between lo and hi,
+// in ascending order. Iteration stops early if the iterator returns false. See BWArr.AscendBounds.
+func (bk *ByKey[T, K]) AscendKeyBounds(lo, hi Bound[K], iterator IteratorFunc[T]) {
+	iter := createAscIteratorWith(bk.BWArr, func(s *segment[T]) (first, last int, ok bool) {
+		return bk.segmentKeyBounds(s, lo, hi)
+	})
+	for val, ok := iter.next(); ok; val, ok = iter.next() {
+		if !iterator(*val) {
+			break
+		}
+	}
+}
+
+// CountKeyBounds returns the number of elements with key between lo and hi.
+// It does not visit the elements, so it has O(Log^2(N)) time complexity.
+func (bk *ByKey[T, K]) CountKeyBounds(lo, hi Bound[K]) int {
+	n := 0
+	for i := range bk.whiteSegments {
+		if bk.total&(1<<i) == 0 {
+			continue
+		}
+		if first, last, ok := bk.segmentKeyBounds(&bk.whiteSegments[i], lo, hi); ok {
+			n += bk.whiteSegments[i].countNonDeleted(first, last)
+		}
+	}
+	return n
+}
+
+func (bk *ByKey[T, K]) searchKey(k K) (segNum, index int) {
+	for segNum = len(bk.whiteSegments) - 1; segNum >= 0; segNum-- {
+		if bk.total&(1<<segNum) == 0 {
+			continue
+		}
+		s := &bk.whiteSegments[segNum]
+		if index = searchRightmostNotDeleted(s, bk.cmpKey, k, s.minNonDeletedIdx, s.maxNonDeletedIdx+1); index >= 0 {
+			return segNum, index
+		}
+	}
+	return -1, -1
+}
+
+// cmpKey compares key k with the key of element e.
+func (bk *ByKey[T, K]) cmpKey(k K, e T) int {
+	return cmp.Compare(k, bk.key(e))
+}
+
+// segmentKeyBounds is BWArr.segmentBounds that compares keys.
+func (bk *ByKey[T, K]) segmentKeyBounds(s *segment[T], lo, hi Bound[K]) (first, last int, ok bool) {
+	if lo.kind == boundUnbounded {
+		first = s.minNonDeletedIndex()
+	} else {
+		first = searchFirstGreater(s, bk.cmpKey, lo.value, lo.kind == boundIncluded, s.minNonDeletedIdx, s.maxNonDeletedIdx+1)
+	}
+	if hi.kind == boundUnbounded {
+		last = s.maxNonDeletedIndex()
+	} else {
+		last = searchLastLess(s, bk.cmpKey, hi.value, hi.kind == boundIncluded, s.minNonDeletedIdx, s.maxNonDeletedIdx+1)
+	}
+	if first < 0 || last < 0 || first > last { // first > last if lo > hi.
+		return 0, 0, false
+	}
+	return first, last, true
+}
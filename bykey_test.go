@@ -0,0 +1,100 @@
+package bwarr
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	id   int
+	name string
+}
+
+func userID(u user) int { return u.id }
+
+func TestByKey(t *testing.T) {
+	t.Parallel()
+	users := NewByKey(userID)
+	users.Insert(user{3, "c"})
+	users.Insert(user{1, "a"})
+	users.Insert(user{2, "b"})
+	users.Insert(user{1, "a2"})
+
+	u, found := users.GetByKey(1)
+	require.True(t, found)
+	assert.Equal(t, user{1, "a"}, u) // The oldest one.
+	assert.True(t, users.HasKey(3))
+	assert.False(t, users.HasKey(4))
+	_, found = users.GetByKey(0)
+	assert.False(t, found)
+
+	u, found = users.DeleteKey(1)
+	require.True(t, found)
+	assert.Equal(t, user{1, "a"}, u)
+	u, _ = users.GetByKey(1)
+	assert.Equal(t, user{1, "a2"}, u)
+	_, found = users.DeleteKey(5)
+	assert.False(t, found)
+	assert.Equal(t, 3, users.Len())
+
+	var got []user
+	users.AscendRangeKeys(1, 3, func(u user) bool {
+		got = append(got, u)
+		return true
+	})
+	assert.Equal(t, []user{{1, "a2"}, {2, "b"}}, got)
+
+	got = got[:0]
+	users.AscendRangeKeys(3, 1, func(u user) bool {
+		got = append(got, u)
+		return true
+	})
+	assert.Empty(t, got)
+}
+
+func TestByKey_MatchesGet(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(7)) //nolint:gosec
+	users := NewByKey(userID)
+	for i := range 3000 {
+		k := r.Intn(300)
+		switch r.Intn(3) {
+		case 0, 1:
+			users.Insert(user{k, ""})
+		case 2:
+			want, wantFound := users.Clone().Delete(user{k, ""})
+			got, gotFound := users.DeleteKey(k)
+			require.Equal(t, wantFound, gotFound, "op %d", i)
+			require.Equal(t, want, got, "op %d", i)
+		}
+		want, wantFound := users.Get(user{k, ""})
+		got, gotFound := users.GetByKey(k)
+		require.Equal(t, wantFound, gotFound, "op %d", i)
+		require.Equal(t, want, got, "op %d", i)
+	}
+
+	var want, got []user
+	users.AscendRange(user{100, ""}, user{200, ""}, func(u user) bool {
+		want = append(want, u)
+		return true
+	})
+	users.AscendRangeKeys(100, 200, func(u user) bool {
+		got = append(got, u)
+		return true
+	})
+	assert.Equal(t, want, got)
+	require.NoError(t, users.Validate())
+}
+
+func TestByKey_Clone(t *testing.T) {
+	t.Parallel()
+	users := NewByKey(userID)
+	users.Insert(user{1, "a"})
+	clone := users.Clone()
+	clone.DeleteKey(1)
+	assert.True(t, users.HasKey(1))
+	assert.False(t, clone.HasKey(1))
+}
@@ -0,0 +1,140 @@
+package bwarr
+
+import (
+	"slices"
+	"unsafe"
+)
+
+// cascadeStep is the sampling step of fractional cascading: every cascadeStep-th entry of a level is copied
+// to the level below, so a position found in a level narrows the search in the level above
+// to cascadeStep entries. With segments doubling in size, levels stay within 4/3 of their segment size.
+const cascadeStep = 8
+
+// cascade keeps fractional cascading levels, one per rank up to the highest active one, as in
+// the cache-oblivious lookahead array (Bender et al., 2007). A level is the segment of the same rank
+// (if active) merged with every cascadeStep-th entry of the level above. Search starts with a binary search
+// in the level of rank 0 and moves up, doing O(1) comparisons per level, so it takes O(Log(N)) in total.
+//
+// Segment entries are referred to by index, so lazy deletions keep levels valid. Sampled entries are copies
+// kept next to each other for cache locality; an element replaced by ReplaceOrInsert may stay referenced
+// by a copy until its segment is rewritten. A rewrite of a segment invalidates the levels of its rank
+// and below, which are rebuilt in time proportional to the segment size, i.e., to the rewrite itself.
+type cascade[T any] struct {
+	levels []cascadeLevel[T]
+}
+
+type cascadeLevel[T any] struct {
+	realBefore []int // realBefore[i] is the number of segment elements among the first i entries.
+	samples    []T   // Entries sampled from the level above, by sample number.
+}
+
+func (l *cascadeLevel[T]) len() int {
+	return len(l.realBefore) - 1
+}
+
+func (c *cascade[T]) clone() *cascade[T] {
+	levels := make([]cascadeLevel[T], len(c.levels))
+	for i := range c.levels {
+		levels[i].realBefore = slices.Clone(c.levels[i].realBefore)
+		levels[i].samples = slices.Clone(c.levels[i].samples)
+	}
+	return &cascade[T]{levels: levels}
+}
+
+func (c *cascade[T]) allocatedBytes() int {
+	var zero T
+	n := 0
+	for i := range c.levels {
+		n += cap(c.levels[i].realBefore) * int(unsafe.Sizeof(int(0)))
+		n += cap(c.levels[i].samples) * int(unsafe.Sizeof(zero))
+	}
+	return n
+}
+
+// cascadeEntry returns the entry i of the level of rank.
+func (bwa *BWArr[T]) cascadeEntry(rank, i int) T {
+	lvl := &bwa.ext.cascade.levels[rank]
+	if before := lvl.realBefore[i]; lvl.realBefore[i+1] > before {
+		return bwa.whiteSegments[rank].elements[before]
+	}
+	return lvl.samples[i-lvl.realBefore[i]]
+}
+
+// rebuildCascade rebuilds the levels of rank and below after the segments of these ranks were rewritten.
+// It must be called after bwa.total is updated.
+func (bwa *BWArr[T]) rebuildCascade(rank int) {
+	top := bwa.maxRank()
+	c := bwa.ext.cascade
+	if n := top + 1; n <= cap(c.levels) {
+		c.levels = c.levels[:n] // Levels above top keep their buffers for reuse.
+	} else {
+		c.levels = append(c.levels[:cap(c.levels)], make([]cascadeLevel[T], n-cap(c.levels))...)
+	}
+	for r := min(rank, top); r >= 0; r-- {
+		bwa.buildCascadeLevel(r)
+	}
+}
+
+func (bwa *BWArr[T]) buildCascadeLevel(rank int) {
+	c := bwa.ext.cascade
+	lvl := &c.levels[rank]
+	var elements []T
+	if bwa.total&(1<<rank) != 0 {
+		elements = bwa.whiteSegments[rank].elements
+	}
+	lvl.samples = lvl.samples[:0]
+	if rank+1 < len(c.levels) {
+		for i := 0; i < c.levels[rank+1].len(); i += cascadeStep {
+			lvl.samples = append(lvl.samples, bwa.cascadeEntry(rank+1, i))
+		}
+	}
+
+	n := len(elements) + len(lvl.samples)
+	lvl.realBefore = slices.Grow(lvl.realBefore[:0], n+1)[:n+1]
+	lvl.realBefore[0] = 0
+	i, j := 0, 0
+	for k := range n {
+		if i < len(elements) && (j == len(lvl.samples) || bwa.cmp(elements[i], lvl.samples[j]) <= 0) {
+			i++
+		} else {
+			j++
+		}
+		lvl.realBefore[k+1] = i
+	}
+}
+
+// searchCascade is search using the cascade levels.
+func (bwa *BWArr[T]) searchCascade(element T) (segNum, index int) {
+	segNum, index = -1, -1
+	c := bwa.ext.cascade
+	if len(c.levels) == 0 {
+		return segNum, index
+	}
+	lo, hi := 0, c.levels[0].len()
+	for rank := range c.levels {
+		for lo < hi { // The first entry not less than element.
+			m := int(uint(lo+hi) >> 1)
+			if bwa.cmp(bwa.cascadeEntry(rank, m), element) < 0 {
+				lo = m + 1
+			} else {
+				hi = m
+			}
+		}
+		lvl := &c.levels[rank]
+		if bwa.total&(1<<rank) != 0 {
+			// Keep searching higher ranks even if found: they hold older elements, which search returns.
+			seg := &bwa.whiteSegments[rank]
+			if first := lvl.realBefore[lo]; first < len(seg.elements) && bwa.cmp(seg.elements[first], element) == 0 {
+				if i, _ := seg.findRightmostNotDeletedFrom(bwa.cmp, element, first); i >= 0 {
+					segNum, index = rank, i
+				}
+			}
+		}
+		if rank+1 < len(c.levels) {
+			// Samples before lo are less than element and the next one is not, which bounds the position above.
+			sampled := lo - lvl.realBefore[lo]
+			lo, hi = max(sampled*cascadeStep-cascadeStep+1, 0), min(sampled*cascadeStep, c.levels[rank+1].len())
+		}
+	}
+	return segNum, index
+}
@@ -0,0 +1,115 @@
+package bwarr
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBWArr_FractionalCascadingMatchesPlain(t *testing.T) {
+	t.Parallel()
+	for _, policy := range []ShrinkPolicy{ShrinkEager, ShrinkNever, ShrinkHysteresis} {
+		r := rand.New(rand.NewSource(19))                                //nolint:gosec
+		opts := Options{FractionalCascading: true, ShrinkPolicy: policy} //nolint:exhaustruct
+		cascading, plain := NewWithOptions(pageItemCmp, 0, opts), New(pageItemCmp, 0)
+		var hint Hint
+		for seq := range int64(8000) {
+			v := pageItem{key: r.Int63n(700), seq: seq}
+			switch r.Intn(7) {
+			case 0, 1:
+				cascading.Insert(v)
+				plain.Insert(v)
+			case 2, 3:
+				got, gotFound := cascading.Delete(v)
+				want, wantFound := plain.Delete(v)
+				require.Equal(t, wantFound, gotFound, "op %d", seq)
+				require.Equal(t, want, got, "op %d", seq)
+			case 4:
+				got, gotFound := cascading.ReplaceOrInsert(v)
+				want, wantFound := plain.ReplaceOrInsert(v)
+				require.Equal(t, wantFound, gotFound, "op %d", seq)
+				require.Equal(t, want, got, "op %d", seq)
+			case 5:
+				got, gotFound := cascading.Get(v)
+				want, wantFound := plain.Get(v)
+				require.Equal(t, wantFound, gotFound, "op %d", seq)
+				require.Equal(t, want, got, "op %d", seq)
+				require.Equal(t, wantFound, cascading.HasHint(v, &hint), "op %d", seq)
+			case 6:
+				switch seq % 1000 {
+				case 0:
+					cascading.Rebuild()
+				case 1:
+					cascading.ShrinkToFit()
+				}
+			}
+		}
+		require.NoError(t, cascading.Validate())
+
+		clone := cascading.Clone()
+		for key := range int64(700) {
+			want, _ := plain.Get(pageItem{key: key})    //nolint:exhaustruct
+			got, _ := cascading.Get(pageItem{key: key}) //nolint:exhaustruct
+			require.Equal(t, want, got, "key %d", key)
+			got, _ = clone.Get(pageItem{key: key}) //nolint:exhaustruct
+			require.Equal(t, want, got, "key %d", key)
+		}
+	}
+}
+
+func TestNewFromSliceWithOptions_FractionalCascading(t *testing.T) {
+	t.Parallel()
+	slice := make([]int64, 1000)
+	for i := range slice {
+		slice[i] = int64(i * 2)
+	}
+	bwa := NewFromSliceWithOptions(int64Cmp, slice, Options{FractionalCascading: true}) //nolint:exhaustruct
+	for i := range int64(2000) {
+		assert.Equal(t, i%2 == 0, bwa.Has(i), "%d", i)
+	}
+	assert.Greater(t, bwa.Stats().BytesAllocated, NewFromSlice(int64Cmp, slice).Stats().BytesAllocated)
+
+	bwa.Clear(false)
+	assert.False(t, bwa.Has(0))
+	bwa.Insert(1)
+	assert.True(t, bwa.Has(1))
+	assert.False(t, bwa.Has(2))
+}
+
+func TestBWArr_FractionalCascadingComparisons(t *testing.T) {
+	t.Parallel()
+	const rank = 16
+	const n = 1<<rank - 1                                                                                // All segments are active, the worst case for the plain search.
+	plain := NewWithOptions(int64Cmp, 0, Options{CountComparisons: true})                                //nolint:exhaustruct
+	cascading := NewWithOptions(int64Cmp, 0, Options{CountComparisons: true, FractionalCascading: true}) //nolint:exhaustruct
+	for i := range int64(n) {
+		plain.Insert(i * 2)
+		cascading.Insert(i * 2)
+	}
+
+	searches := func(bwa *BWArr[int64]) uint64 {
+		before := bwa.Stats().Comparisons
+		for i := range int64(2 * n) {
+			require.Equal(t, i%2 == 0, bwa.Has(i))
+		}
+		return (bwa.Stats().Comparisons - before) / (2 * n)
+	}
+	plainCmps, cascadingCmps := searches(plain), searches(cascading)
+	assert.LessOrEqual(t, cascadingCmps, uint64(5*rank), "O(1) comparisons per rank")
+	assert.Less(t, cascadingCmps, plainCmps)
+}
+
+func TestNewWithOptions_FractionalCascadingWithFilter(t *testing.T) {
+	t.Parallel()
+	bwa := NewWithOptions(int64Cmp, 0, Options{FractionalCascading: true, Filter: BloomFilter(int64Hash, 0, 10)}) //nolint:exhaustruct
+	assert.Nil(t, bwa.ext.bloom)
+	assert.NotNil(t, bwa.ext.cascade)
+	for i := range int64(100) {
+		bwa.Insert(i)
+	}
+	assert.True(t, bwa.Has(42))
+	assert.False(t, bwa.Has(100))
+	require.NoError(t, bwa.Validate())
+}
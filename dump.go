@@ -0,0 +1,127 @@
+package bwarr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotMaxElems limits the number of elements rendered per segment by WriteDOT.
+const dotMaxElems = 32
+
+// Dump writes the internal layout of the BWArr to w: every rank with its state and, for
+// active segments, all elements in their physical order. Lazy-deleted elements are prefixed with "~".
+// The output is intended for humans, its format is not stable.
+//
+//	BWArr len=6 total=7
+//	rank 0: active [6]
+//	rank 1: active [4 5]
+//	rank 2: active [0 1 2 ~3] deleted=1
+//	rank 3: inactive
+func (bwa *BWArr[T]) Dump(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "BWArr len=%d total=%d\n", bwa.Len(), bwa.total)
+	for rank := range bwa.whiteSegments {
+		seg := &bwa.whiteSegments[rank]
+		fmt.Fprintf(bw, "rank %d: ", rank)
+		switch {
+		case bwa.total&(1<<rank) != 0:
+			bw.WriteString("active [")
+			for i := range seg.elements {
+				if i > 0 {
+					bw.WriteByte(' ')
+				}
+				if isDeletedBit(seg.deleted, i) {
+					bw.WriteByte('~')
+				}
+				fmt.Fprintf(bw, "%v", seg.elements[i])
+			}
+			bw.WriteByte(']')
+			if seg.deletedNum > 0 {
+				fmt.Fprintf(bw, " deleted=%d", seg.deletedNum)
+			}
+		case len(seg.elements) > 0:
+			bw.WriteString("inactive")
+		default:
+			bw.WriteString("released")
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// Format implements fmt.Formatter. The %+v verb prints the internal layout as Dump does.
+// Other verbs print non-deleted elements in ascending order like a slice, formatting every element
+// with the same verb and flags as fmt does for slices, e.g., [1 2 3] for %v or %d and [01 02 03] for %02d.
+func (bwa *BWArr[T]) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') {
+		_ = bwa.Dump(f)
+		return
+	}
+	format := fmt.FormatString(f, verb)
+	io.WriteString(f, "[") //nolint:errcheck
+	first := true
+	bwa.Ascend(func(item T) bool {
+		if !first {
+			io.WriteString(f, " ") //nolint:errcheck
+		}
+		first = false
+		fmt.Fprintf(f, format, item)
+		return true
+	})
+	io.WriteString(f, "]") //nolint:errcheck
+}
+
+// String returns non-deleted elements in ascending order, formatted like a slice.
+func (bwa *BWArr[T]) String() string {
+	return fmt.Sprintf("%v", bwa)
+}
+
+// WriteDOT renders the segments of the BWArr in the Graphviz DOT language, e.g., for
+// `dot -Tsvg`. Active segments are drawn solid with their elements, deleted elements are
+// prefixed with "~"; inactive segments are dashed and gray. At most 32 elements are
+// rendered per segment.
+func (bwa *BWArr[T]) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph BWArr {\n\tnode [shape=record, fontname=\"monospace\"];\n")
+	for rank := range bwa.whiteSegments {
+		seg := &bwa.whiteSegments[rank]
+		fmt.Fprintf(bw, "\tseg%d [label=\"rank %d", rank, rank)
+		if bwa.total&(1<<rank) == 0 {
+			state := "inactive"
+			if len(seg.elements) == 0 {
+				state = "released"
+			}
+			fmt.Fprintf(bw, "|%s\", style=\"dashed,filled\", fillcolor=lightgray];\n", state)
+			continue
+		}
+		bw.WriteString("|{")
+		for i := range min(len(seg.elements), dotMaxElems) {
+			if i > 0 {
+				bw.WriteByte('|')
+			}
+			if isDeletedBit(seg.deleted, i) {
+				bw.WriteByte('~')
+			}
+			bw.WriteString(escapeDOTRecord(fmt.Sprintf("%v", seg.elements[i])))
+		}
+		if len(seg.elements) > dotMaxElems {
+			fmt.Fprintf(bw, "|%d more", len(seg.elements)-dotMaxElems)
+		}
+		fmt.Fprintf(bw, "}\", style=solid];\n")
+	}
+	for rank := 1; rank < len(bwa.whiteSegments); rank++ {
+		fmt.Fprintf(bw, "\tseg%d -> seg%d [style=invis];\n", rank-1, rank)
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+var dotRecordEscaper = strings.NewReplacer(
+	`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`, "\n", `\n`,
+)
+
+func escapeDOTRecord(s string) string {
+	return dotRecordEscaper.Replace(s)
+}
@@ -0,0 +1,72 @@
+package bwarr
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeDumpTestBWArr() *BWArr[int64] {
+	bwa := New(int64Cmp, 0)
+	for i := range int64(7) {
+		bwa.Insert(i)
+	}
+	bwa.Delete(3)
+	bwa.whiteSegments = append(bwa.whiteSegments, makeSegment[int64](3), segment[int64]{}) //nolint:exhaustruct
+	return bwa
+}
+
+func TestBWArr_Dump(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, makeDumpTestBWArr().Dump(&buf))
+	assert.Equal(t, `BWArr len=6 total=7
+rank 0: active [6]
+rank 1: active [4 5]
+rank 2: active [0 1 2 ~3] deleted=1
+rank 3: inactive
+rank 4: released
+`, buf.String())
+}
+
+func TestBWArr_Format(t *testing.T) {
+	t.Parallel()
+	bwa := makeDumpTestBWArr()
+	assert.Equal(t, "[0 1 2 4 5 6]", fmt.Sprintf("%v", bwa))
+	assert.Equal(t, "[0 1 2 4 5 6]", fmt.Sprint(bwa))
+	assert.Equal(t, "[0 1 2 4 5 6]", bwa.String())
+	assert.Equal(t, "[]", New(int64Cmp, 0).String())
+	assert.Contains(t, fmt.Sprintf("%+v", bwa), "rank 2: active [0 1 2 ~3] deleted=1\n")
+	assert.Equal(t, "[0 1 2 4 5 6]", fmt.Sprintf("%d", bwa))
+	assert.Equal(t, "[00 01 02 04 05 06]", fmt.Sprintf("%02d", bwa))
+	assert.Equal(t, "[%!s(int64=0) %!s(int64=1) %!s(int64=2) %!s(int64=4) %!s(int64=5) %!s(int64=6)]",
+		fmt.Sprintf("%s", bwa), "bad verbs are reported per element, as for slices")
+}
+
+func TestBWArr_WriteDOT(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, makeDumpTestBWArr().WriteDOT(&buf))
+	assert.Equal(t, `digraph BWArr {
+	node [shape=record, fontname="monospace"];
+	seg0 [label="rank 0|{6}", style=solid];
+	seg1 [label="rank 1|{4|5}", style=solid];
+	seg2 [label="rank 2|{0|1|2|~3}", style=solid];
+	seg3 [label="rank 3|inactive", style="dashed,filled", fillcolor=lightgray];
+	seg4 [label="rank 4|released", style="dashed,filled", fillcolor=lightgray];
+	seg0 -> seg1 [style=invis];
+	seg1 -> seg2 [style=invis];
+	seg2 -> seg3 [style=invis];
+	seg3 -> seg4 [style=invis];
+}
+`, buf.String())
+
+	strs := New(func(a, b string) int { return len(a) - len(b) }, 0)
+	strs.Insert(`{"a|b"}`)
+	buf.Reset()
+	require.NoError(t, strs.WriteDOT(&buf))
+	assert.Contains(t, buf.String(), `label="rank 0|{\{\"a\|b\"\}}"`)
+}
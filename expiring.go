@@ -0,0 +1,156 @@
+package bwarr
+
+import (
+	"time"
+)
+
+// Expiring is a sorted collection of elements with deadlines. Expired elements, whose deadline is not after
+// the current time, are skipped by lookups and iteration, and removed in bulk by PurgeExpired. Elements are
+// kept in two BWArrs: one ordered by element and one ordered by deadline, so a purge takes O(Log^2(N))
+// per removed element and does not look at the live ones.
+//
+// Equal elements with different deadlines may coexist; lookups see the live one with the earliest deadline.
+// Expiring is not safe for concurrent use.
+type Expiring[T any] struct {
+	elements  *BWArr[expiringEntry[T]] // Ordered by element, then by deadline.
+	deadlines *BWArr[expiringEntry[T]] // Ordered by deadline, then by element.
+	cmp       CmpFunc[T]
+	now       func() time.Time
+}
+
+type expiringEntry[T any] struct {
+	element  T
+	deadline time.Time
+}
+
+// NewExpiring creates a new empty Expiring ordered by cmp. now returns the current time to check deadlines
+// against; nil means time.Now. Tests can pass a fake clock to be deterministic.
+func NewExpiring[T any](cmp CmpFunc[T], now func() time.Time) *Expiring[T] {
+	if now == nil {
+		now = time.Now
+	}
+	byElement := func(a, b expiringEntry[T]) int {
+		if c := cmp(a.element, b.element); c != 0 {
+			return c
+		}
+		return a.deadline.Compare(b.deadline)
+	}
+	byDeadline := func(a, b expiringEntry[T]) int {
+		if c := a.deadline.Compare(b.deadline); c != 0 {
+			return c
+		}
+		return cmp(a.element, b.element)
+	}
+	return &Expiring[T]{elements: New(byElement, 0), deadlines: New(byDeadline, 0), cmp: cmp, now: now}
+}
+
+// Insert adds an element that expires at deadline.
+func (e *Expiring[T]) Insert(element T, deadline time.Time) {
+	entry := expiringEntry[T]{element: element, deadline: deadline}
+	e.elements.Insert(entry)
+	e.deadlines.Insert(entry)
+}
+
+// InsertTTL adds an element that expires after ttl from now.
+func (e *Expiring[T]) InsertTTL(element T, ttl time.Duration) {
+	e.Insert(element, e.now().Add(ttl))
+}
+
+// ReplaceOrInsert replaces the live element equal to the given one, also setting its deadline,
+// or inserts the element if there is no such element. It can be used to extend sessions.
+func (e *Expiring[T]) ReplaceOrInsert(element T, deadline time.Time) (old T, found bool) {
+	if entry, ok := e.live(element, e.now()); ok {
+		old, found = e.remove(entry).element, true
+	}
+	e.Insert(element, deadline)
+	return old, found
+}
+
+// Get returns the live element equal to the given one.
+func (e *Expiring[T]) Get(element T) (res T, found bool) {
+	entry, found := e.live(element, e.now())
+	return entry.element, found
+}
+
+// GetDeadline is Get that also returns the deadline of the element.
+func (e *Expiring[T]) GetDeadline(element T) (res T, deadline time.Time, found bool) {
+	entry, found := e.live(element, e.now())
+	return entry.element, entry.deadline, found
+}
+
+// Has returns true if there is a live element equal to the given one.
+func (e *Expiring[T]) Has(element T) bool {
+	_, found := e.live(element, e.now())
+	return found
+}
+
+// Delete removes the live element equal to the given one and returns it.
+func (e *Expiring[T]) Delete(element T) (deleted T, found bool) {
+	entry, found := e.live(element, e.now())
+	if !found {
+		return deleted, false
+	}
+	return e.remove(entry).element, true
+}
+
+// Len returns the number of elements, including the expired ones that are not purged yet.
+func (e *Expiring[T]) Len() int {
+	return e.elements.Len()
+}
+
+// Ascend calls the iterator for every live element in ascending order, until it returns false.
+func (e *Expiring[T]) Ascend(iterator IteratorFunc[T]) {
+	now := e.now()
+	e.elements.Ascend(func(entry expiringEntry[T]) bool {
+		return !entry.deadline.After(now) || iterator(entry.element)
+	})
+}
+
+// Descend calls the iterator for every live element in descending order, until it returns false.
+func (e *Expiring[T]) Descend(iterator IteratorFunc[T]) {
+	now := e.now()
+	e.elements.Descend(func(entry expiringEntry[T]) bool {
+		return !entry.deadline.After(now) || iterator(entry.element)
+	})
+}
+
+// NextDeadline returns the earliest deadline of the stored elements, including expired ones. It is the time
+// to schedule the next PurgeExpired at; false means the collection is empty.
+func (e *Expiring[T]) NextDeadline() (deadline time.Time, found bool) {
+	entry, found := e.deadlines.Min()
+	return entry.deadline, found
+}
+
+// PurgeExpired removes the elements expired at now and returns their number. It looks only at the elements
+// removed, in the order of their deadlines, so it can be called often, e.g., by a ticker or at NextDeadline.
+func (e *Expiring[T]) PurgeExpired(now time.Time) int {
+	n := 0
+	for {
+		entry, found := e.deadlines.Min()
+		if !found || entry.deadline.After(now) {
+			return n
+		}
+		e.deadlines.DeleteMin()
+		e.elements.Delete(entry)
+		n++
+	}
+}
+
+// live returns the entry of the element equal to the given one with the earliest deadline after now.
+func (e *Expiring[T]) live(element T, now time.Time) (res expiringEntry[T], found bool) {
+	from := Excluded(expiringEntry[T]{element: element, deadline: now})
+	e.elements.AscendBounds(from, Unbounded[expiringEntry[T]](), func(entry expiringEntry[T]) bool {
+		if e.cmp(entry.element, element) == 0 {
+			res, found = entry, true
+		}
+		return false
+	})
+	return res, found
+}
+
+// remove deletes the entry from both BWArrs.
+func (e *Expiring[T]) remove(entry expiringEntry[T]) expiringEntry[T] {
+	deleted, _ := e.elements.Delete(entry)
+	e.deadlines.Delete(entry)
+	return deleted
+}
@@ -0,0 +1,151 @@
+package bwarr
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func collectExpiring(e *Expiring[pageItem], desc bool) []pageItem {
+	var res []pageItem
+	iter := func(v pageItem) bool {
+		res = append(res, v)
+		return true
+	}
+	if desc {
+		e.Descend(iter)
+	} else {
+		e.Ascend(iter)
+	}
+	return res
+}
+
+func TestExpiring_LazyExpiry(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	e := NewExpiring(pageItemCmp, clock.Now)
+	e.InsertTTL(pageItem{key: 1, seq: 0}, time.Minute)
+	e.InsertTTL(pageItem{key: 2, seq: 1}, 3*time.Minute)
+	e.InsertTTL(pageItem{key: 3, seq: 2}, 2*time.Minute)
+
+	assert.True(t, e.Has(pageItem{key: 1}))  //nolint:exhaustruct
+	clock.Advance(time.Minute)               // The deadline is reached, so the element is expired.
+	assert.False(t, e.Has(pageItem{key: 1})) //nolint:exhaustruct
+	_, found := e.Get(pageItem{key: 1})      //nolint:exhaustruct
+	assert.False(t, found)
+	_, found = e.Delete(pageItem{key: 1}) //nolint:exhaustruct
+	assert.False(t, found)
+
+	got, deadline, found := e.GetDeadline(pageItem{key: 3}) //nolint:exhaustruct
+	assert.True(t, found)
+	assert.Equal(t, pageItem{key: 3, seq: 2}, got)
+	assert.Equal(t, clock.now.Add(time.Minute), deadline)
+
+	assert.Equal(t, []pageItem{{2, 1}, {3, 2}}, collectExpiring(e, false))
+	assert.Equal(t, []pageItem{{3, 2}, {2, 1}}, collectExpiring(e, true))
+	assert.Equal(t, 3, e.Len()) // Expired elements are kept until purged.
+}
+
+func TestExpiring_EqualElementsWithDifferentDeadlines(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	e := NewExpiring(pageItemCmp, clock.Now)
+	e.InsertTTL(pageItem{key: 1, seq: 0}, 2*time.Minute)
+	e.InsertTTL(pageItem{key: 1, seq: 1}, time.Minute)
+
+	got, _ := e.Get(pageItem{key: 1}) //nolint:exhaustruct
+	assert.Equal(t, pageItem{key: 1, seq: 1}, got, "the earliest deadline goes first")
+	clock.Advance(time.Minute)
+	got, _ = e.Get(pageItem{key: 1}) //nolint:exhaustruct
+	assert.Equal(t, pageItem{key: 1, seq: 0}, got)
+
+	old, found := e.ReplaceOrInsert(pageItem{key: 1, seq: 2}, clock.now.Add(time.Hour))
+	assert.True(t, found)
+	assert.Equal(t, pageItem{key: 1, seq: 0}, old)
+	clock.Advance(30 * time.Minute)
+	assert.Equal(t, []pageItem{{1, 2}}, collectExpiring(e, false))
+
+	assert.Equal(t, 1, e.PurgeExpired(clock.now))
+	deleted, found := e.Delete(pageItem{key: 1}) //nolint:exhaustruct
+	assert.True(t, found)
+	assert.Equal(t, pageItem{key: 1, seq: 2}, deleted)
+	assert.Equal(t, 0, e.Len())
+}
+
+func TestExpiring_PurgeExpired(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(31)) //nolint:gosec
+	clock := newFakeClock()
+	e := NewExpiring(pageItemCmp, clock.Now)
+	_, found := e.NextDeadline()
+	assert.False(t, found)
+
+	deadlines := map[pageItem]time.Time{}
+	for seq := range int64(2000) {
+		v := pageItem{key: r.Int63n(300), seq: seq}
+		deadlines[v] = clock.now.Add(time.Duration(r.Int63n(1000)) * time.Second)
+		e.Insert(v, deadlines[v])
+		if seq%100 == 0 {
+			clock.Advance(10 * time.Second)
+		}
+	}
+
+	for range 20 {
+		clock.Advance(time.Duration(r.Int63n(200)) * time.Second)
+		var expired int
+		for _, d := range deadlines {
+			if !d.After(clock.now) {
+				expired++
+			}
+		}
+		next, found := e.NextDeadline()
+		if e.Len() > 0 {
+			require.True(t, found)
+			require.Equal(t, expired > 0, !next.After(clock.now))
+		}
+		require.Equal(t, expired, e.PurgeExpired(clock.now))
+		for v, d := range deadlines {
+			if !d.After(clock.now) {
+				delete(deadlines, v)
+			}
+		}
+		require.Equal(t, len(deadlines), e.Len())
+
+		var live int
+		e.Ascend(func(pageItem) bool {
+			live++
+			return true
+		})
+		require.Equal(t, len(deadlines), live)
+	}
+	require.NoError(t, e.elements.Validate())
+	require.NoError(t, e.deadlines.Validate())
+}
+
+func TestNewExpiring_DefaultClock(t *testing.T) {
+	t.Parallel()
+	e := NewExpiring(int64Cmp, nil)
+	e.InsertTTL(1, time.Hour)
+	e.Insert(2, time.Now().Add(-time.Second))
+	assert.True(t, e.Has(1))
+	assert.False(t, e.Has(2))
+}
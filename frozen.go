@@ -0,0 +1,224 @@
+package bwarr
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// Frozen file layout:
+//
+//	header:   magic | version (uint16) | byte order mark (uint16) | element size (uint32) | number of elements (uint64)
+//	padding:  up to frozenHeaderSize bytes, so elements are aligned in a memory-mapped file
+//	elements: raw memory of the elements in ascending order
+const (
+	frozenMagic      = "BWFZ"
+	frozenVersion    = 1
+	frozenByteOrder  = 0x0102
+	frozenHeaderSize = 64
+	frozenChunkBytes = 1 << 12 // Freeze copies equal elements in chunks of this size to write them oldest first.
+)
+
+// ErrNotFreezable is returned when the element type cannot be stored in a frozen file.
+var ErrNotFreezable = errors.New("bwarr: element type must be fixed-size and pointer-free")
+
+// ErrBadFrozenFile is returned when a file is not a frozen BWArr of the requested element type.
+var ErrBadFrozenFile = errors.New("bwarr: bad frozen file")
+
+// Freeze writes all elements of the BWArr to w as a single sorted run that can be opened with OpenFrozen.
+// T must be fixed-size and pointer-free (no pointers, strings, slices, maps, channels, functions or
+// interfaces). The layout uses the memory representation of T, so the file is readable only on
+// machines with the same byte order and the same layout of T.
+//
+// Equal elements are stored in the FIFO order (oldest first), the same way Page returns them.
+// The operation has O(N + D*Log^2(N)) time complexity, where D is the number of distinct elements.
+func (bwa *BWArr[T]) Freeze(w io.Writer) error {
+	if err := checkFreezable(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return err
+	}
+	var header [frozenHeaderSize]byte
+	copy(header[:], frozenMagic)
+	binary.LittleEndian.PutUint16(header[4:], frozenVersion)
+	*(*uint16)(unsafe.Pointer(&header[6])) = frozenByteOrder                  // Native byte order.
+	binary.LittleEndian.PutUint32(header[8:], uint32(unsafe.Sizeof(*new(T)))) //nolint:gosec
+	binary.LittleEndian.PutUint64(header[12:], uint64(bwa.Len()))             //nolint:gosec
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	elemSize := int(unsafe.Sizeof(*new(T)))
+	if bwa.Len() == 0 || elemSize == 0 {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+	chunk := make([]T, 0, max(1, frozenChunkBytes/elemSize))
+	for key, found := bwa.nextKey(Unbounded[T](), false); found; key, found = bwa.nextKey(Excluded(key), false) {
+		for skip, groupLen := 0, 1; skip < groupLen; skip += len(chunk) { // groupLen is known after the first chunk.
+			chunk, groupLen = bwa.appendEqualFIFO(chunk[:0], key, skip, cap(chunk))
+			if _, err := bw.Write(unsafe.Slice((*byte)(unsafe.Pointer(&chunk[0])), len(chunk)*elemSize)); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Frozen is a read-only sorted collection backed by a memory-mapped file written by BWArr.Freeze.
+// Elements are used in place without deserialization, so processes that open the same file share
+// one copy of it in the page cache. Frozen is safe for concurrent use until Close is called.
+type Frozen[T any] struct {
+	elems []T
+	cmp   CmpFunc[T]
+	data  []byte
+}
+
+// OpenFrozen maps the file written by BWArr.Freeze into memory. The comparison function must
+// define the same ordering as the one of the frozen BWArr. Call Close to release the mapping.
+func OpenFrozen[T any](path string, cmp CmpFunc[T]) (*Frozen[T], error) {
+	if err := checkFreezable(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() < frozenHeaderSize {
+		return nil, fmt.Errorf("%w: file is too short", ErrBadFrozenFile)
+	}
+	data, err := mapFile(f, int(st.Size()))
+	if err != nil {
+		return nil, err
+	}
+	fz := &Frozen[T]{cmp: cmp, data: data} //nolint:exhaustruct
+	if err = fz.init(); err != nil {
+		return nil, errors.Join(err, unmapFile(data))
+	}
+	return fz, nil
+}
+
+func (fz *Frozen[T]) init() error {
+	header := fz.data[:frozenHeaderSize]
+	elemSize := uint64(unsafe.Sizeof(*new(T)))
+	switch {
+	case string(header[:4]) != frozenMagic:
+		return fmt.Errorf("%w: bad magic", ErrBadFrozenFile)
+	case binary.LittleEndian.Uint16(header[4:]) != frozenVersion:
+		return fmt.Errorf("%w: unsupported version", ErrBadFrozenFile)
+	case *(*uint16)(unsafe.Pointer(&header[6])) != frozenByteOrder:
+		return fmt.Errorf("%w: byte order mismatch", ErrBadFrozenFile)
+	case uint64(binary.LittleEndian.Uint32(header[8:])) != elemSize:
+		return fmt.Errorf("%w: element size mismatch", ErrBadFrozenFile)
+	}
+	count := binary.LittleEndian.Uint64(header[12:])
+	if count*elemSize != uint64(len(fz.data)-frozenHeaderSize) {
+		return fmt.Errorf("%w: file size does not match number of elements", ErrBadFrozenFile)
+	}
+	switch {
+	case count > 0 && elemSize == 0:
+		fz.elems = make([]T, count)
+	case count > 0:
+		fz.elems = unsafe.Slice((*T)(unsafe.Pointer(&fz.data[frozenHeaderSize])), count)
+	}
+	return nil
+}
+
+// Close releases the memory mapping. Elements returned earlier stay valid because they are copies,
+// but Frozen must not be used after Close.
+func (fz *Frozen[T]) Close() error {
+	fz.elems = nil
+	data := fz.data
+	fz.data = nil
+	return unmapFile(data)
+}
+
+// Len returns the number of elements.
+func (fz *Frozen[T]) Len() int {
+	return len(fz.elems)
+}
+
+// Get returns the first element equal to the given one and true if found,
+// or the zero value of T and false if not found. The operation has O(log N) time complexity.
+//
+// When multiple equal elements exist, the first inserted element is returned, as BWArr.Get does.
+func (fz *Frozen[T]) Get(element T) (res T, found bool) {
+	i := fz.Rank(element)
+	if i < len(fz.elems) && fz.cmp(fz.elems[i], element) == 0 {
+		return fz.elems[i], true
+	}
+	return res, false
+}
+
+// Has returns true if the element exists, false otherwise.
+func (fz *Frozen[T]) Has(element T) bool {
+	_, found := fz.Get(element)
+	return found
+}
+
+// Rank returns the number of elements that are less than the given one.
+func (fz *Frozen[T]) Rank(element T) int {
+	return sort.Search(len(fz.elems), func(i int) bool { return fz.cmp(fz.elems[i], element) >= 0 })
+}
+
+// Ascend calls the iterator function for each element in ascending order.
+// Iteration stops early if the iterator returns false.
+func (fz *Frozen[T]) Ascend(iterator IteratorFunc[T]) {
+	fz.ascend(0, len(fz.elems), iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator function for each element that is greater than or equal
+// to the given one, in ascending order. Iteration stops early if the iterator returns false.
+func (fz *Frozen[T]) AscendGreaterOrEqual(elem T, iterator IteratorFunc[T]) {
+	fz.ascend(fz.Rank(elem), len(fz.elems), iterator)
+}
+
+// AscendLessThan calls the iterator function for each element that is less than the given one,
+// in ascending order. Iteration stops early if the iterator returns false.
+func (fz *Frozen[T]) AscendLessThan(elem T, iterator IteratorFunc[T]) {
+	fz.ascend(0, fz.Rank(elem), iterator)
+}
+
+// AscendRange calls the iterator function for each element that is greater than or equal to
+// greaterOrEqual and less than lessThan, in ascending order. Iteration stops early if the
+// iterator returns false.
+func (fz *Frozen[T]) AscendRange(greaterOrEqual, lessThan T, iterator IteratorFunc[T]) {
+	fz.ascend(fz.Rank(greaterOrEqual), fz.Rank(lessThan), iterator)
+}
+
+func (fz *Frozen[T]) ascend(from, to int, iterator IteratorFunc[T]) {
+	for i := from; i < to; i++ {
+		if !iterator(fz.elems[i]) {
+			return
+		}
+	}
+}
+
+func checkFreezable(t reflect.Type) error {
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return nil
+	case reflect.Array:
+		return checkFreezable(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if err := checkFreezable(t.Field(i).Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s contains %s", ErrNotFreezable, t, t.Kind())
+	}
+}
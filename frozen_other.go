@@ -0,0 +1,24 @@
+//go:build !unix
+
+package bwarr
+
+import (
+	"io"
+	"os"
+	"unsafe"
+)
+
+// mapFile reads the whole file on platforms without mmap support.
+// The buffer is backed by uint64 words to keep the elements aligned.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	words := make([]uint64, (size+7)/8) //nolint:mnd
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmapFile([]byte) error {
+	return nil
+}
@@ -0,0 +1,189 @@
+package bwarr
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBWArr_FreezeAndOpen(t *testing.T) {
+	t.Parallel()
+	const elemsNum = 1000
+	bwa := New(int64Cmp, elemsNum)
+	expected := make([]int64, 0, elemsNum)
+	for range elemsNum {
+		v := int64(rand.Intn(elemsNum))
+		bwa.Insert(v)
+		expected = append(expected, v)
+	}
+	for i := range 100 { // Deleted elements must not be frozen.
+		bwa.Delete(expected[i])
+	}
+	expected = expected[100:]
+	slices.Sort(expected)
+
+	fz := freezeToFile(t, bwa, int64Cmp)
+	defer fz.Close()
+
+	require.Equal(t, len(expected), fz.Len())
+	var got []int64
+	fz.Ascend(func(item int64) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.Equal(t, expected, got)
+
+	for _, v := range expected {
+		e, found := fz.Get(v)
+		require.True(t, found)
+		require.Equal(t, v, e)
+	}
+	assert.False(t, fz.Has(elemsNum))
+	assert.Equal(t, 0, fz.Rank(-1))
+	assert.Equal(t, len(expected), fz.Rank(elemsNum))
+	rank := fz.Rank(expected[len(expected)/2])
+	assert.Equal(t, expected[len(expected)/2], expected[rank])
+	if rank > 0 {
+		assert.Less(t, expected[rank-1], expected[rank])
+	}
+
+	got = got[:0]
+	fz.AscendRange(100, 200, func(item int64) bool {
+		got = append(got, item)
+		return true
+	})
+	from, _ := slices.BinarySearch(expected, 100)
+	to, _ := slices.BinarySearch(expected, 200)
+	assert.Equal(t, expected[from:to], got)
+
+	got = got[:0]
+	fz.AscendGreaterOrEqual(900, func(item int64) bool {
+		got = append(got, item)
+		return len(got) < 3
+	})
+	from, _ = slices.BinarySearch(expected, 900)
+	assert.Equal(t, expected[from:from+3], got)
+
+	got = got[:0]
+	fz.AscendLessThan(50, func(item int64) bool {
+		got = append(got, item)
+		return true
+	})
+	to, _ = slices.BinarySearch(expected, 50)
+	assert.Equal(t, expected[:to], got)
+}
+
+func TestBWArr_FreezeStruct(t *testing.T) {
+	t.Parallel()
+	type point struct {
+		X   int32
+		Y   float64
+		Tag [3]byte
+	}
+	pointCmp := func(a, b point) int { return int(a.X - b.X) }
+	bwa := New(pointCmp, 0)
+	for i := range 10 {
+		bwa.Insert(point{X: int32(10 - i), Y: float64(i) / 2, Tag: [3]byte{byte(i)}})
+	}
+
+	fz := freezeToFile(t, bwa, pointCmp)
+	defer fz.Close()
+	p, found := fz.Get(point{X: 3})
+	require.True(t, found)
+	assert.Equal(t, point{X: 3, Y: 3.5, Tag: [3]byte{7}}, p)
+}
+
+func TestBWArr_FreezeFIFO(t *testing.T) {
+	t.Parallel()
+	type entry struct {
+		Key int64
+		Seq int64
+	}
+	entryCmp := func(a, b entry) int { return int(a.Key - b.Key) }
+	bwa := New(entryCmp, 0)
+	expected := make([]entry, 0)
+	r := rand.New(rand.NewSource(3))
+	for i := range 3000 { // Large groups span several chunks of the writer.
+		e := entry{Key: int64(r.Intn(4)), Seq: int64(i)}
+		bwa.Insert(e)
+		expected = append(expected, e)
+	}
+	for i := range 500 { // Deletes the oldest equal elements.
+		_, found := bwa.Delete(entry{Key: int64(i % 4)})
+		require.True(t, found)
+		idx := slices.IndexFunc(expected, func(e entry) bool { return e.Key == int64(i%4) })
+		expected = slices.Delete(expected, idx, idx+1)
+	}
+	slices.SortStableFunc(expected, entryCmp)
+
+	fz := freezeToFile(t, bwa, entryCmp)
+	defer fz.Close()
+	var got []entry
+	fz.Ascend(func(e entry) bool {
+		got = append(got, e)
+		return true
+	})
+	assert.Equal(t, expected, got)
+	for key := range int64(4) {
+		e, found := fz.Get(entry{Key: key})
+		require.True(t, found)
+		oldest, _ := bwa.Get(entry{Key: key})
+		assert.Equal(t, oldest, e)
+	}
+}
+
+func TestBWArr_FreezeEmpty(t *testing.T) {
+	t.Parallel()
+	fz := freezeToFile(t, New(int64Cmp, 0), int64Cmp)
+	defer fz.Close()
+	assert.Equal(t, 0, fz.Len())
+	assert.False(t, fz.Has(42))
+	fz.Ascend(func(int64) bool {
+		t.Fail()
+		return true
+	})
+}
+
+func TestBWArr_FreezeRejectsPointers(t *testing.T) {
+	t.Parallel()
+	bwa := New(testStructCmp, 0)
+	err := bwa.Freeze(&bytes.Buffer{})
+	require.ErrorIs(t, err, ErrNotFreezable)
+	_, err = OpenFrozen[testStruct]("does-not-matter", testStructCmp)
+	require.ErrorIs(t, err, ErrNotFreezable)
+}
+
+func TestOpenFrozen_TypeMismatch(t *testing.T) {
+	t.Parallel()
+	bwa := New(int64Cmp, 0)
+	bwa.Insert(42)
+	path := filepath.Join(t.TempDir(), "frozen")
+	var buf bytes.Buffer
+	require.NoError(t, bwa.Freeze(&buf))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	_, err := OpenFrozen[int32](path, func(a, b int32) int { return int(a - b) })
+	require.ErrorIs(t, err, ErrBadFrozenFile)
+
+	require.NoError(t, os.WriteFile(path, buf.Bytes()[:frozenHeaderSize+4], 0o600))
+	_, err = OpenFrozen[int64](path, int64Cmp)
+	require.ErrorIs(t, err, ErrBadFrozenFile)
+}
+
+func freezeToFile[T any](t *testing.T, bwa *BWArr[T], cmp CmpFunc[T]) *Frozen[T] {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "frozen")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, bwa.Freeze(f))
+	require.NoError(t, f.Close())
+	fz, err := OpenFrozen[T](path, cmp)
+	require.NoError(t, err)
+	return fz
+}
@@ -0,0 +1,136 @@
+package bwarr
+
+import "math/bits"
+
+// Hint remembers where the previous hinted operation found its element in every segment, like PathHint
+// in github.com/google/btree. The next hinted search in a segment starts from that position and gallops
+// towards the element, so lookups of near-sorted or clustered keys take O(1) per segment and O(Log(N))
+// in total instead of O(Log^2(N)).
+//
+// A hint never affects results: positions moved by merges and deletions only make the search longer,
+// up to twice the plain binary search. The zero value is ready to use, and a nil *Hint disables hinting.
+// A Hint must not be used by concurrent operations.
+type Hint struct {
+	pos [bits.UintSize]int // Per-rank position of the previous search.
+}
+
+// GetHint is Get that starts searching from the positions remembered in hint and updates them.
+func (bwa *BWArr[T]) GetHint(element T, hint *Hint) (res T, found bool) {
+	if segNum, index := bwa.searchHint(element, hint); index >= 0 {
+		return bwa.whiteSegments[segNum].elements[index], true
+	}
+	return
+}
+
+// HasHint is Has that starts searching from the positions remembered in hint and updates them.
+func (bwa *BWArr[T]) HasHint(element T, hint *Hint) bool {
+	_, index := bwa.searchHint(element, hint)
+	return index >= 0
+}
+
+// InsertHint is Insert. Insert does not search, so the hint is not used; it is accepted so code paths
+// working with hints can use one API. The hint stays usable, positions moved by the insertion are
+// corrected by the following hinted searches.
+func (bwa *BWArr[T]) InsertHint(element T, _ *Hint) {
+	bwa.Insert(element)
+}
+
+// ReplaceOrInsertHint is ReplaceOrInsert that starts searching from the positions remembered in hint
+// and updates them.
+func (bwa *BWArr[T]) ReplaceOrInsertHint(element T, hint *Hint) (old T, found bool) {
+	seg, ind := bwa.searchHint(element, hint)
+	if ind < 0 {
+		bwa.Insert(element)
+		return old, false
+	}
+	old = bwa.whiteSegments[seg].elements[ind]
+	bwa.whiteSegments[seg].elements[ind] = element
+	return old, true
+}
+
+// DeleteHint is Delete that starts searching from the positions remembered in hint and updates them.
+func (bwa *BWArr[T]) DeleteHint(element T, hint *Hint) (deleted T, found bool) {
+	segNum, index := bwa.searchHint(element, hint)
+	if segNum < 0 {
+		return deleted, false
+	}
+	return bwa.del(segNum, index), true
+}
+
+func (bwa *BWArr[T]) searchHint(element T, hint *Hint) (segNum, index int) {
+	if hint == nil || bwa.cascading() { // Cascading search is O(Log(N)) anyway.
+		return bwa.search(element)
+	}
+	var bloom *bloomFilters[T]
+	if bwa.ext != nil {
+		bloom = bwa.ext.bloom
+	}
+	var hash uint64
+	if bloom != nil {
+		hash = bloom.hash(element)
+	}
+	for segNum = len(bwa.whiteSegments) - 1; segNum >= 0; segNum-- {
+		if bwa.total&(1<<segNum) == 0 {
+			continue
+		}
+		if bloom != nil && !bloom.mayContain(segNum, hash) {
+			continue
+		}
+		index, hint.pos[segNum] = bwa.whiteSegments[segNum].findRightmostNotDeletedFrom(bwa.cmp, element, hint.pos[segNum])
+		if index >= 0 {
+			return segNum, index
+		}
+	}
+	return -1, -1
+}
+
+// findRightmostNotDeletedFrom is findRightmostNotDeleted that gallops from start instead of
+// bisecting the whole segment. It also returns the position to start the next search from.
+func (s *segment[T]) findRightmostNotDeletedFrom(cmp CmpFunc[T], val T, start int) (index, next int) {
+	b, e := s.minNonDeletedIdx, s.maxNonDeletedIdx+1
+	if b >= e {
+		return -1, start
+	}
+	// goRight is true for elements before the rightmost non-deleted element equal to val, inclusive:
+	// deleted elements are to the right (higher index) of non-deleted equal ones.
+	goRight := func(i int) bool {
+		c := cmp(val, s.elements[i])
+		return c > 0 || (c == 0 && !isDeletedBit(s.deleted, i))
+	}
+
+	// Narrow [b, e] down to the range containing the first element with !goRight.
+	start = min(max(start, b), e-1)
+	if goRight(start) {
+		b = start + 1
+		for step := 1; start+step < e; step <<= 1 {
+			if !goRight(start + step) {
+				e = start + step
+				break
+			}
+			b = start + step + 1
+		}
+	} else {
+		e = start
+		for step := 1; start-step >= b; step <<= 1 {
+			if goRight(start - step) {
+				b = start - step + 1
+				break
+			}
+			e = start - step
+		}
+	}
+	for b < e {
+		m := (b + e) >> 1
+		if goRight(m) {
+			b = m + 1
+		} else {
+			e = m
+		}
+	}
+
+	idx := b - 1
+	if idx < s.minNonDeletedIdx || isDeletedBit(s.deleted, idx) || cmp(s.elements[idx], val) != 0 {
+		return -1, b
+	}
+	return idx, b
+}
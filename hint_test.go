@@ -0,0 +1,96 @@
+package bwarr
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBWArr_HintMatchesPlainSearch(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(13)) //nolint:gosec
+	hinted, plain := New(pageItemCmp, 0), New(pageItemCmp, 0)
+	var hint Hint
+	for seq := range int64(5000) {
+		v := pageItem{key: r.Int63n(300), seq: seq}
+		if r.Intn(5) == 0 { // Jump around sometimes, so the hint is far from the element.
+			v.key = seq % 300
+		}
+		switch r.Intn(5) {
+		case 0, 1:
+			hinted.InsertHint(v, &hint)
+			plain.Insert(v)
+		case 2:
+			got, gotFound := hinted.DeleteHint(v, &hint)
+			want, wantFound := plain.Delete(v)
+			require.Equal(t, wantFound, gotFound, "op %d", seq)
+			require.Equal(t, want, got, "op %d", seq)
+		case 3:
+			got, gotFound := hinted.ReplaceOrInsertHint(v, &hint)
+			want, wantFound := plain.ReplaceOrInsert(v)
+			require.Equal(t, wantFound, gotFound, "op %d", seq)
+			require.Equal(t, want, got, "op %d", seq)
+		case 4:
+			got, gotFound := hinted.GetHint(v, &hint)
+			want, wantFound := plain.Get(v)
+			require.Equal(t, wantFound, gotFound, "op %d", seq)
+			require.Equal(t, want, got, "op %d", seq)
+			require.Equal(t, wantFound, hinted.HasHint(v, &hint))
+		}
+	}
+	require.NoError(t, hinted.Validate())
+}
+
+func TestBWArr_HintNil(t *testing.T) {
+	t.Parallel()
+	bwa := NewFromSlice(int64Cmp, []int64{3, 1, 2})
+	got, found := bwa.GetHint(2, nil)
+	assert.True(t, found)
+	assert.Equal(t, int64(2), got)
+	_, found = bwa.DeleteHint(5, nil)
+	assert.False(t, found)
+}
+
+func TestBWArr_HintReducesComparisons(t *testing.T) {
+	t.Parallel()
+	const n = 1<<14 - 1
+	bwa := NewWithOptions(int64Cmp, n, Options{CountComparisons: true}) //nolint:exhaustruct
+	for i := range int64(n) {
+		bwa.Insert(i * 2)
+	}
+
+	before := bwa.Stats().Comparisons
+	for i := range int64(n) {
+		bwa.Has(i * 2)
+	}
+	plain := bwa.Stats().Comparisons - before
+
+	var hint Hint
+	before = bwa.Stats().Comparisons
+	for i := range int64(n) {
+		bwa.HasHint(i*2, &hint)
+	}
+	hinted := bwa.Stats().Comparisons - before
+
+	assert.Less(t, hinted*3, plain, "hinted %d, plain %d", hinted, plain)
+}
+
+//nolint:exhaustruct
+func Test_segment_findRightmostNotDeletedFrom(t *testing.T) {
+	t.Parallel()
+	seg := segment[int64]{
+		elements:         []int64{1, 3, 3, 3, 5, 7, 7, 9},
+		deleted:          delBitmap(false, false, false, true, false, false, true, false),
+		deletedNum:       2,
+		maxNonDeletedIdx: 7,
+	}
+	for _, val := range []int64{0, 1, 2, 3, 5, 6, 7, 9, 10} {
+		want := seg.findRightmostNotDeleted(int64Cmp, val)
+		for start := -3; start < 12; start++ {
+			got, _ := seg.findRightmostNotDeletedFrom(int64Cmp, val, start)
+			assert.Equal(t, want, got, "val %d start %d", val, start)
+		}
+	}
+}
@@ -15,224 +15,6 @@ type segmentIterator[T any] struct {
 	end   int
 }
 
-func createAscIteratorBegin[T any](bwa *BWArr[T]) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		idx := bwa.whiteSegments[i].minNonDeletedIndex()
-		end := bwa.whiteSegments[i].maxNonDeletedIndex()
-		si[i] = segmentIterator[T]{index: idx, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s1.seg.elements[s1.index], s2.seg.elements[s2.index])
-	})
-
-	return iter
-}
-
-func createAscIteratorGTOE[T any](bwa *BWArr[T], elem T) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		idx := bwa.whiteSegments[i].findGTOE(bwa.cmp, elem)
-		if idx < 0 {
-			continue
-		}
-		end := bwa.whiteSegments[i].maxNonDeletedIndex()
-		si[i] = segmentIterator[T]{index: idx, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s1.seg.elements[s1.index], s2.seg.elements[s2.index])
-	})
-
-	return iter
-}
-
-func createAscIteratorLess[T any](bwa *BWArr[T], elem T) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		end := bwa.whiteSegments[i].findLess(bwa.cmp, elem)
-		if end < 0 {
-			continue
-		}
-		idx := bwa.whiteSegments[i].minNonDeletedIndex()
-		si[i] = segmentIterator[T]{index: idx, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s1.seg.elements[s1.index], s2.seg.elements[s2.index])
-	})
-
-	return iter
-}
-
-func createAscIteratorFromTo[T any](bwa *BWArr[T], from, to T) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		end := bwa.whiteSegments[i].findLess(bwa.cmp, to)
-		if end < 0 {
-			continue
-		}
-		begin := bwa.whiteSegments[i].findGTOE(bwa.cmp, from)
-		if begin < 0 {
-			continue
-		}
-
-		si[i] = segmentIterator[T]{index: begin, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s1.seg.elements[s1.index], s2.seg.elements[s2.index])
-	})
-
-	return iter
-}
-
-func createDescIteratorEnd[T any](bwa *BWArr[T]) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		idx := bwa.whiteSegments[i].maxNonDeletedIndex()
-		end := bwa.whiteSegments[i].minNonDeletedIndex()
-		si[i] = segmentIterator[T]{index: idx, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s2.seg.elements[s2.index], s1.seg.elements[s1.index])
-	})
-
-	return iter
-}
-
-func createDescIteratorGTOE[T any](bwa *BWArr[T], elem T) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		end := bwa.whiteSegments[i].findGTOE(bwa.cmp, elem)
-		if end < 0 {
-			continue
-		}
-		idx := bwa.whiteSegments[i].maxNonDeletedIndex()
-		si[i] = segmentIterator[T]{index: idx, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s2.seg.elements[s2.index], s1.seg.elements[s1.index])
-	})
-
-	return iter
-}
-
-func createDescIteratorLess[T any](bwa *BWArr[T], elem T) iterator[T] {
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		idx := bwa.whiteSegments[i].findLess(bwa.cmp, elem)
-		if idx < 0 {
-			continue
-		}
-		end := bwa.whiteSegments[i].minNonDeletedIdx
-		si[i] = segmentIterator[T]{index: idx, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s2.seg.elements[s2.index], s1.seg.elements[s1.index])
-	})
-
-	return iter
-}
-
-func createDescIteratorFromTo[T any](bwa *BWArr[T], from, to T) iterator[T] { //nolint:dupl
-	iter := iterator[T]{
-		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
-		cmp:      bwa.cmp,
-	}
-
-	si := make([]segmentIterator[T], len(bwa.whiteSegments))
-	for i := range bwa.whiteSegments {
-		if bwa.total&(1<<i) == 0 {
-			continue
-		}
-		end := bwa.whiteSegments[i].findGTOE(bwa.cmp, from)
-		if end < 0 {
-			continue
-		}
-		begin := bwa.whiteSegments[i].findLess(bwa.cmp, to)
-		if begin < 0 {
-			continue
-		}
-
-		si[i] = segmentIterator[T]{index: begin, seg: bwa.whiteSegments[i], end: end}
-		iter.segIters = append(iter.segIters, &si[i])
-	}
-
-	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
-		return iter.cmp(s2.seg.elements[s2.index], s1.seg.elements[s1.index])
-	})
-
-	return iter
-}
-
 func (iter *iterator[T]) next() (*T, bool) { //nolint:dupl
 	if len(iter.segIters) == 0 {
 		return nil, false
@@ -329,3 +111,93 @@ func (iter *iterator[T]) cmpSegIters(i, j int) int {
 	s1, s2 := iter.segIters[i], iter.segIters[j]
 	return iter.cmp(s1.seg.elements[s1.index], s2.seg.elements[s2.index])
 }
+
+// segmentBounds returns indexes of the first and the last non-deleted elements of the segment within bounds.
+// ok is false if there are no such elements.
+func (bwa *BWArr[T]) segmentBounds(s *segment[T], lo, hi Bound[T]) (first, last int, ok bool) {
+	if lo.kind == boundUnbounded {
+		first = s.minNonDeletedIndex()
+	} else {
+		first = bwa.findFirstGreater(s, lo.value, lo.kind == boundIncluded)
+	}
+	if hi.kind == boundUnbounded {
+		last = s.maxNonDeletedIndex()
+	} else {
+		last = bwa.findLastLess(s, hi.value, hi.kind == boundIncluded)
+	}
+	if first < 0 || last < 0 || first > last { // first > last if lo > hi.
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+func createAscIteratorBounds[T any](bwa *BWArr[T], lo, hi Bound[T]) iterator[T] {
+	return createAscIteratorWith(bwa, func(s *segment[T]) (first, last int, ok bool) {
+		return bwa.segmentBounds(s, lo, hi)
+	})
+}
+
+func createDescIteratorBounds[T any](bwa *BWArr[T], lo, hi Bound[T]) iterator[T] {
+	return createDescIteratorWith(bwa, func(s *segment[T]) (first, last int, ok bool) {
+		return bwa.segmentBounds(s, lo, hi)
+	})
+}
+
+// segmentBoundsFunc returns indexes of the first and the last non-deleted elements of the segment to iterate over.
+// ok is false if there are no such elements.
+type segmentBoundsFunc[T any] func(s *segment[T]) (first, last int, ok bool)
+
+// createAscIteratorWith creates an ascending iterator over the elements of every segment from
+// the first to the last index returned by bounds.
+func createAscIteratorWith[T any](bwa *BWArr[T], bounds segmentBoundsFunc[T]) iterator[T] { //nolint:dupl
+	iter := iterator[T]{
+		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
+		cmp:      bwa.cmp,
+	}
+
+	si := make([]segmentIterator[T], len(bwa.whiteSegments))
+	for i := range bwa.whiteSegments {
+		if bwa.total&(1<<i) == 0 {
+			continue
+		}
+		first, last, ok := bounds(&bwa.whiteSegments[i])
+		if !ok {
+			continue
+		}
+		si[i] = segmentIterator[T]{index: first, seg: bwa.whiteSegments[i], end: last}
+		iter.segIters = append(iter.segIters, &si[i])
+	}
+
+	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
+		return iter.cmp(s1.seg.elements[s1.index], s2.seg.elements[s2.index])
+	})
+
+	return iter
+}
+
+// createDescIteratorWith is createAscIteratorWith that iterates in descending order.
+func createDescIteratorWith[T any](bwa *BWArr[T], bounds segmentBoundsFunc[T]) iterator[T] { //nolint:dupl
+	iter := iterator[T]{
+		segIters: make([]*segmentIterator[T], 0, len(bwa.whiteSegments)),
+		cmp:      bwa.cmp,
+	}
+
+	si := make([]segmentIterator[T], len(bwa.whiteSegments))
+	for i := range bwa.whiteSegments {
+		if bwa.total&(1<<i) == 0 {
+			continue
+		}
+		first, last, ok := bounds(&bwa.whiteSegments[i])
+		if !ok {
+			continue
+		}
+		si[i] = segmentIterator[T]{index: last, seg: bwa.whiteSegments[i], end: first}
+		iter.segIters = append(iter.segIters, &si[i])
+	}
+
+	slices.SortFunc(iter.segIters, func(s1, s2 *segmentIterator[T]) int {
+		return iter.cmp(s2.seg.elements[s2.index], s1.seg.elements[s1.index])
+	})
+
+	return iter
+}
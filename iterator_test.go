@@ -6,7 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCreateDescIteratorEnd(t *testing.T) {
+func TestCreateDescIteratorBounds_Unbounded(t *testing.T) {
 	t.Parallel()
 
 	// Initialize a BWArr with some segments
@@ -16,11 +16,11 @@ func TestCreateDescIteratorEnd(t *testing.T) {
 	}
 
 	// Mark some elements as deleted
-	bwa.whiteSegments[2].deleted[0] = true // Delete first element of third segment
-	bwa.whiteSegments[2].deleted[3] = true // Delete last element of third segment
+	bwa.whiteSegments[2].markDeleted(0) // Delete first element of third segment
+	bwa.whiteSegments[2].markDeleted(3) // Delete last element of third segment
 	bwa.whiteSegments[2].deletedNum += 2
 
-	iter := createDescIteratorEnd(bwa)
+	iter := createDescIteratorBounds(bwa, Unbounded[int64](), Unbounded[int64]())
 
 	expectedIndices := []int{0, 1, 2}
 	expectedLengths := []int{1, 2, 4}
@@ -33,7 +33,7 @@ func TestCreateDescIteratorEnd(t *testing.T) {
 	}
 }
 
-func TestCreateAscIteratorBegin(t *testing.T) {
+func TestCreateAscIteratorBounds_Unbounded(t *testing.T) {
 	t.Parallel()
 
 	// Initialize a BWArr with some segments
@@ -43,11 +43,11 @@ func TestCreateAscIteratorBegin(t *testing.T) {
 	}
 
 	// Mark some elements as deleted
-	bwa.whiteSegments[2].deleted[0] = true // Delete first element of third segment
-	bwa.whiteSegments[2].deleted[3] = true // Delete last element of third segment
+	bwa.whiteSegments[2].markDeleted(0) // Delete first element of third segment
+	bwa.whiteSegments[2].markDeleted(3) // Delete last element of third segment
 	bwa.whiteSegments[2].deletedNum += 2
 
-	iter := createAscIteratorBegin(bwa)
+	iter := createAscIteratorBounds(bwa, Unbounded[int64](), Unbounded[int64]())
 
 	expectedIndices := []int{1, 0, 0}
 	expectedLengths := []int{4, 2, 1}
@@ -0,0 +1,154 @@
+// Package llrbcompat provides LLRB, a drop-in replacement for github.com/petar/GoLLRB/llrb
+// backed by bwarr.BWArr. To migrate, change the import:
+//
+//	import llrb "github.com/dronnix/bwarr/llrbcompat"
+//
+// Like GoLLRB, LLRB is a multiset: InsertNoReplace adds an item even if equal items exist.
+// Among equal items, Get, Delete and ReplaceOrInsert use the oldest one.
+// Methods exposing the tree structure (Root, SetRoot, GetHeight, HeightStats) are not provided.
+package llrbcompat
+
+import (
+	"cmp"
+
+	"github.com/dronnix/bwarr"
+)
+
+// Item is an element of the tree.
+type Item interface {
+	Less(than Item) bool
+}
+
+// ItemIterator is called for every visited item. Iteration stops if it returns false.
+type ItemIterator func(i Item) bool
+
+// LLRB is an ordered multiset of items with the API of GoLLRB.
+type LLRB struct {
+	bwa *bwarr.BWArr[Item]
+}
+
+// New allocates a new tree.
+func New() *LLRB {
+	return &LLRB{bwa: bwarr.New(compare, 0)}
+}
+
+// Len returns the number of items in the tree.
+func (t *LLRB) Len() int {
+	return t.bwa.Len()
+}
+
+// Has returns true if the tree contains an item equal to key.
+func (t *LLRB) Has(key Item) bool {
+	return t.bwa.Has(key)
+}
+
+// Get retrieves an item equal to key, or nil if there is none.
+func (t *LLRB) Get(key Item) Item {
+	item, _ := t.bwa.Get(key)
+	return item
+}
+
+// Min returns the minimum item in the tree, or nil if the tree is empty.
+func (t *LLRB) Min() Item {
+	item, _ := t.bwa.Min()
+	return item
+}
+
+// Max returns the maximum item in the tree, or nil if the tree is empty.
+func (t *LLRB) Max() Item {
+	item, _ := t.bwa.Max()
+	return item
+}
+
+// ReplaceOrInsertBulk calls ReplaceOrInsert for every item.
+func (t *LLRB) ReplaceOrInsertBulk(items ...Item) {
+	for _, item := range items {
+		t.ReplaceOrInsert(item)
+	}
+}
+
+// InsertNoReplaceBulk calls InsertNoReplace for every item.
+func (t *LLRB) InsertNoReplaceBulk(items ...Item) {
+	for _, item := range items {
+		t.InsertNoReplace(item)
+	}
+}
+
+// ReplaceOrInsert inserts item into the tree. If an equal item exists, it is replaced
+// and returned. Otherwise, nil is returned. Panics if item is nil, as GoLLRB does.
+func (t *LLRB) ReplaceOrInsert(item Item) Item {
+	if item == nil {
+		panic("inserting nil item")
+	}
+	old, _ := t.bwa.ReplaceOrInsert(item)
+	return old
+}
+
+// InsertNoReplace inserts item into the tree. If equal items exist, item is added next to them.
+// Panics if item is nil, as GoLLRB does.
+func (t *LLRB) InsertNoReplace(item Item) {
+	if item == nil {
+		panic("inserting nil item")
+	}
+	t.bwa.Insert(item)
+}
+
+// DeleteMin deletes the minimum item in the tree and returns it, or nil if the tree is empty.
+func (t *LLRB) DeleteMin() Item {
+	item, _ := t.bwa.DeleteMin()
+	return item
+}
+
+// DeleteMax deletes the maximum item in the tree and returns it, or nil if the tree is empty.
+func (t *LLRB) DeleteMax() Item {
+	item, _ := t.bwa.DeleteMax()
+	return item
+}
+
+// Delete deletes an item equal to key and returns it, or nil if there is none.
+func (t *LLRB) Delete(key Item) Item {
+	item, _ := t.bwa.Delete(key)
+	return item
+}
+
+// AscendGreaterOrEqual calls iterator for every item greater than or equal to pivot, in ascending order.
+func (t *LLRB) AscendGreaterOrEqual(pivot Item, iterator ItemIterator) {
+	t.bwa.AscendGreaterOrEqual(pivot, bwarr.IteratorFunc[Item](iterator))
+}
+
+// AscendRange calls iterator for every item in [greaterOrEqual, lessThan), in ascending order.
+func (t *LLRB) AscendRange(greaterOrEqual, lessThan Item, iterator ItemIterator) {
+	t.bwa.AscendRange(greaterOrEqual, lessThan, bwarr.IteratorFunc[Item](iterator))
+}
+
+// AscendLessThan calls iterator for every item less than pivot, in ascending order.
+func (t *LLRB) AscendLessThan(pivot Item, iterator ItemIterator) {
+	t.bwa.AscendLessThan(pivot, bwarr.IteratorFunc[Item](iterator))
+}
+
+// DescendLessOrEqual calls iterator for every item less than or equal to pivot, in descending order.
+func (t *LLRB) DescendLessOrEqual(pivot Item, iterator ItemIterator) {
+	t.bwa.DescendLessOrEqual(pivot, bwarr.IteratorFunc[Item](iterator))
+}
+
+// compare orders items by their Less method. Inf items are handled here,
+// so Less of user items never sees them.
+func compare(a, b Item) int {
+	if x, ok := a.(inf); ok {
+		if y, ok := b.(inf); ok {
+			return cmp.Compare(x, y)
+		}
+		return int(x)
+	}
+	if y, ok := b.(inf); ok {
+		return -int(y)
+	}
+	switch {
+	case a.Less(b):
+		return -1
+	case b.Less(a):
+		return 1
+	default:
+		return 0
+	}
+}
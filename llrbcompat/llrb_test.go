@@ -0,0 +1,279 @@
+package llrbcompat
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Conformance tests follow the behavior of github.com/petar/GoLLRB's tests.
+
+func collect(out *[]Item) ItemIterator {
+	return func(i Item) bool {
+		*out = append(*out, i)
+		return true
+	}
+}
+
+func ints(vals ...int) []Item {
+	out := make([]Item, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, Int(v))
+	}
+	return out
+}
+
+func TestCases(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	tree.ReplaceOrInsert(Int(1))
+	tree.ReplaceOrInsert(Int(1))
+	assert.Equal(t, 1, tree.Len())
+	assert.Equal(t, Int(1), tree.Get(Int(1)))
+	assert.Equal(t, Int(1), tree.Delete(Int(1)))
+	assert.Equal(t, 0, tree.Len())
+
+	tree.ReplaceOrInsert(Int(0))
+	tree.ReplaceOrInsert(Int(2))
+	tree.ReplaceOrInsert(Int(3))
+	tree.ReplaceOrInsert(Int(4))
+	tree.ReplaceOrInsert(Int(1))
+	assert.Equal(t, 5, tree.Len())
+	assert.Equal(t, Int(0), tree.Min())
+	assert.Equal(t, Int(4), tree.Max())
+	assert.Nil(t, tree.Get(Int(7)))
+	assert.Nil(t, tree.Delete(Int(7)))
+}
+
+func TestReverseInsertOrder(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 100
+	for i := range n {
+		tree.ReplaceOrInsert(Int(n - i))
+	}
+	i := 0
+	tree.AscendGreaterOrEqual(Int(0), func(item Item) bool {
+		i++
+		assert.Equal(t, Int(i), item)
+		return true
+	})
+	assert.Equal(t, n, i)
+}
+
+func TestRange(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	order := []String{"ab", "aba", "abc", "a", "aa", "aaa", "b", "a-", "a!"}
+	for _, i := range order {
+		tree.ReplaceOrInsert(i)
+	}
+	var got []Item
+	tree.AscendRange(String("ab"), String("ac"), collect(&got))
+	assert.Equal(t, []Item{String("ab"), String("aba"), String("abc")}, got)
+}
+
+func TestRandomInsertOrder(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 1000
+	for _, i := range rand.Perm(n) {
+		tree.ReplaceOrInsert(Int(i))
+	}
+	j := 0
+	tree.AscendGreaterOrEqual(Int(0), func(item Item) bool {
+		assert.Equal(t, Int(j), item)
+		j++
+		return true
+	})
+	assert.Equal(t, n, j)
+}
+
+func TestRandomReplace(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 100
+	for _, i := range rand.Perm(n) {
+		tree.ReplaceOrInsert(Int(i))
+	}
+	for _, i := range rand.Perm(n) {
+		assert.Equal(t, Int(i), tree.ReplaceOrInsert(Int(i)))
+	}
+	assert.Equal(t, n, tree.Len())
+}
+
+func TestRandomInsertSequentialDelete(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 1000
+	for _, i := range rand.Perm(n) {
+		tree.ReplaceOrInsert(Int(i))
+	}
+	for i := range n {
+		assert.Equal(t, Int(i), tree.Delete(Int(i)))
+	}
+	assert.Equal(t, 0, tree.Len())
+}
+
+func TestRandomInsertDeleteNonExistent(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 100
+	for _, i := range rand.Perm(n) {
+		tree.ReplaceOrInsert(Int(i))
+	}
+	assert.Nil(t, tree.Delete(Int(200)))
+	assert.Nil(t, tree.Delete(Int(-2)))
+	for _, i := range rand.Perm(n) {
+		assert.Equal(t, Int(i), tree.Delete(Int(i)))
+	}
+	assert.Nil(t, tree.Delete(Int(200)))
+	assert.Nil(t, tree.Delete(Int(-2)))
+}
+
+func TestRandomInsertPartialDeleteOrder(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 100
+	for _, i := range rand.Perm(n) {
+		tree.ReplaceOrInsert(Int(i))
+	}
+	for i := 1; i < n-1; i++ {
+		tree.Delete(Int(i))
+	}
+	var got []Item
+	tree.AscendGreaterOrEqual(Int(0), collect(&got))
+	assert.Equal(t, ints(0, n-1), got)
+}
+
+func TestInsertNoReplace(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	const n = 1000
+	for q := range 2 {
+		for _, i := range rand.Perm(n) {
+			tree.InsertNoReplace(Int(i))
+		}
+		assert.Equal(t, (q+1)*n, tree.Len())
+	}
+	j := 0
+	tree.AscendGreaterOrEqual(Int(0), func(item Item) bool {
+		assert.Equal(t, Int(j/2), item)
+		j++
+		return true
+	})
+	assert.Equal(t, 2*n, j)
+	for i := range n {
+		require.Equal(t, Int(i), tree.DeleteMin())
+		require.Equal(t, Int(i), tree.DeleteMin())
+	}
+	assert.Nil(t, tree.DeleteMin())
+}
+
+type kv struct {
+	k int
+	v string
+}
+
+func (x kv) Less(than Item) bool {
+	return x.k < than.(kv).k //nolint:forcetypeassert
+}
+
+func TestInsertNoReplaceKeepsAllEqual(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	tree.InsertNoReplace(kv{1, "a"})
+	tree.InsertNoReplaceBulk(kv{1, "b"}, kv{1, "c"})
+	assert.PanicsWithValue(t, "inserting nil item", func() { tree.InsertNoReplace(nil) })
+	assert.PanicsWithValue(t, "inserting nil item", func() { tree.ReplaceOrInsert(nil) })
+	assert.Equal(t, 3, tree.Len())
+	assert.True(t, tree.Has(kv{1, ""}))
+	// The oldest of equal items is returned and deleted first.
+	assert.Equal(t, kv{1, "a"}, tree.Get(kv{1, ""}))
+	assert.Equal(t, kv{1, "a"}, tree.Delete(kv{1, ""}))
+	assert.Equal(t, kv{1, "b"}, tree.ReplaceOrInsert(kv{1, "d"}))
+	var got []Item
+	tree.AscendGreaterOrEqual(kv{1, ""}, collect(&got))
+	assert.ElementsMatch(t, []Item{kv{1, "c"}, kv{1, "d"}}, got)
+	got = got[:0]
+	tree.DescendLessOrEqual(kv{1, ""}, collect(&got))
+	assert.ElementsMatch(t, []Item{kv{1, "c"}, kv{1, "d"}}, got)
+}
+
+func TestBulk(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	tree.InsertNoReplaceBulk(ints(3, 1, 2, 1)...)
+	assert.Equal(t, 4, tree.Len())
+	tree.ReplaceOrInsertBulk(ints(1, 2, 3, 4)...)
+	assert.Equal(t, 5, tree.Len())
+	var got []Item
+	tree.AscendGreaterOrEqual(Inf(-1), collect(&got))
+	assert.Equal(t, ints(1, 1, 2, 3, 4), got)
+}
+
+func TestDeleteMinMax(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	assert.Nil(t, tree.Min())
+	assert.Nil(t, tree.Max())
+	assert.Nil(t, tree.DeleteMin())
+	assert.Nil(t, tree.DeleteMax())
+	tree.InsertNoReplaceBulk(ints(2, 5, 1, 4, 3)...)
+	assert.Equal(t, Int(1), tree.DeleteMin())
+	assert.Equal(t, Int(5), tree.DeleteMax())
+	assert.Equal(t, Int(2), tree.Min())
+	assert.Equal(t, Int(4), tree.Max())
+	assert.Equal(t, 3, tree.Len())
+}
+
+func TestIterations(t *testing.T) {
+	t.Parallel()
+	tree := New()
+	tree.InsertNoReplaceBulk(ints(5, 1, 3, 3, 7, 9)...)
+
+	var got []Item
+	tree.AscendLessThan(Int(5), collect(&got))
+	assert.Equal(t, ints(1, 3, 3), got)
+
+	got = got[:0]
+	tree.DescendLessOrEqual(Int(5), collect(&got))
+	assert.Equal(t, ints(5, 3, 3, 1), got)
+
+	got = got[:0]
+	tree.DescendLessOrEqual(Int(3), collect(&got))
+	assert.Equal(t, ints(3, 3, 1), got)
+
+	got = got[:0]
+	tree.DescendLessOrEqual(Int(4), collect(&got))
+	assert.Equal(t, ints(3, 3, 1), got)
+
+	got = got[:0]
+	tree.DescendLessOrEqual(Inf(1), collect(&got))
+	assert.Equal(t, ints(9, 7, 5, 3, 3, 1), got)
+
+	got = got[:0]
+	tree.AscendRange(Int(3), Inf(1), collect(&got))
+	assert.Equal(t, ints(3, 3, 5, 7, 9), got)
+
+	got = got[:0]
+	tree.AscendGreaterOrEqual(Int(3), func(i Item) bool {
+		got = append(got, i)
+		return i.Less(Int(5))
+	})
+	assert.Equal(t, ints(3, 3, 5), got)
+}
+
+func TestInf(t *testing.T) {
+	t.Parallel()
+	assert.True(t, Inf(-1).Less(Int(0)))
+	assert.False(t, Inf(1).Less(Int(0)))
+	assert.True(t, Inf(-1).Less(Inf(1)))
+	assert.False(t, Inf(1).Less(Inf(1)))
+	assert.Equal(t, -1, compare(Inf(-1), Inf(1)))
+	assert.Equal(t, 0, compare(Inf(1), Inf(1)))
+	assert.Equal(t, 1, compare(Int(0), Inf(-1)))
+	assert.Equal(t, -1, compare(Int(0), Inf(1)))
+}
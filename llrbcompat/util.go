@@ -0,0 +1,41 @@
+package llrbcompat
+
+// Int is an Item of int keys.
+type Int int
+
+// Less returns true if x is less than than.
+func (x Int) Less(than Item) bool {
+	return x < than.(Int) //nolint:forcetypeassert
+}
+
+// String is an Item of string keys.
+type String string
+
+// Less returns true if x is less than than.
+func (x String) Less(than Item) bool {
+	return x < than.(String) //nolint:forcetypeassert
+}
+
+type inf int
+
+const (
+	ninf inf = -1
+	pinf inf = 1
+)
+
+// Less implements Item. Comparisons with other items are handled by the tree.
+func (x inf) Less(than Item) bool {
+	if y, ok := than.(inf); ok {
+		return x < y
+	}
+	return x == ninf
+}
+
+// Inf returns an Item that is greater than any other item if sign is positive,
+// and less than any other item otherwise. It can be used as a bound of iterations.
+func Inf(sign int) Item {
+	if sign > 0 {
+		return pinf
+	}
+	return ninf
+}
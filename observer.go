@@ -0,0 +1,22 @@
+package bwarr
+
+import "time"
+
+// Observer is notified about internal operations of a BWArr, e.g., to correlate latency spikes
+// with merges or to export the memory usage to a metrics system. Set it with Options.Observer.
+// Methods are called synchronously from the operation that triggered them, so they must be fast
+// and must not use the BWArr. If no observer is set, the notifications cost nothing.
+type Observer interface {
+	// OnMerge is called after segments of ranks fromRank..toRank-1 were merged into the segment of
+	// rank toRank, which holds elems elements now, including lazy-deleted ones.
+	OnMerge(fromRank, toRank, elems int, duration time.Duration)
+	// OnDemote is called after the segment of rank became half-deleted and its
+	// non-deleted elements were moved to the unused segment of rank-1.
+	OnDemote(rank int)
+	// OnSegmentAlloc is called after memory for the segment of rank was allocated.
+	// Segments reused from Options.Pool are not reported.
+	OnSegmentAlloc(rank, bytes int)
+	// OnSegmentRelease is called when memory of the segment of rank is released.
+	// Segments kept by Options.Pool for reuse are not reported.
+	OnSegmentRelease(rank, bytes int)
+}
@@ -0,0 +1,86 @@
+package bwarr
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) OnMerge(fromRank, toRank, elems int, _ time.Duration) {
+	o.events = append(o.events, fmt.Sprintf("merge %d..%d -> %d (%d)", fromRank, toRank-1, toRank, elems))
+}
+
+func (o *recordingObserver) OnDemote(rank int) {
+	o.events = append(o.events, fmt.Sprintf("demote %d", rank))
+}
+
+func (o *recordingObserver) OnSegmentAlloc(rank, bytes int) {
+	o.events = append(o.events, fmt.Sprintf("alloc %d (%d bytes)", rank, bytes))
+}
+
+func (o *recordingObserver) OnSegmentRelease(rank, bytes int) {
+	o.events = append(o.events, fmt.Sprintf("release %d (%d bytes)", rank, bytes))
+}
+
+func TestBWArr_Observer(t *testing.T) {
+	t.Parallel()
+	obs := &recordingObserver{}
+	bwa := NewWithOptions(int64Cmp, 0, Options{Observer: obs}) //nolint:exhaustruct
+	for i := range int64(4) {
+		bwa.Insert(i)
+	}
+	bwa.Delete(0)
+	bwa.Delete(1)
+	bwa.Insert(4)
+	bwa.Delete(4)
+	bwa.Compact()
+	bwa.Clear(true)
+
+	assert.Equal(t, []string{
+		"alloc 0 (8 bytes)",
+		"alloc 1 (16 bytes)",
+		"merge 0..0 -> 1 (2)",
+		"alloc 2 (32 bytes)",
+		"merge 0..1 -> 2 (4)",
+		"demote 2",
+		"release 2 (40 bytes)", // The deletion allocated the bitmap of the segment.
+		"release 0 (16 bytes)",
+		"release 1 (16 bytes)",
+	}, obs.events)
+}
+
+func TestBWArr_ObserverOnDeleteMerge(t *testing.T) {
+	t.Parallel()
+	obs := &recordingObserver{}
+	bwa := NewWithOptions(int64Cmp, 8, Options{Observer: obs}) //nolint:exhaustruct
+	assert.Equal(t, []string{"alloc 0 (8 bytes)", "alloc 1 (16 bytes)", "alloc 2 (32 bytes)", "alloc 3 (64 bytes)"}, obs.events)
+	for i := range int64(6) {
+		bwa.Insert(i)
+	}
+	obs.events = nil
+	bwa.Delete(0)
+	bwa.Delete(1) // Half of the segment of rank 2 is deleted, rank 1 is active, so they are merged.
+	assert.Equal(t, []string{"merge 1..1 -> 2 (4)"}, obs.events)
+}
+
+func TestBWArr_ObserverWithPool(t *testing.T) {
+	t.Parallel()
+	obs := &recordingObserver{}
+	opts := Options{Observer: obs, Pool: NewSegmentPool[int64](1)} //nolint:exhaustruct
+	a := NewWithOptions(int64Cmp, 0, opts)
+	b := NewWithOptions(int64Cmp, 0, opts)
+	a.Insert(1)
+	b.Insert(1)
+	a.Clear(true) // The pool keeps the segment.
+	b.Clear(true) // The pool is full, the segment is released.
+	c := NewWithOptions(int64Cmp, 0, opts)
+	c.Insert(1) // The segment is reused.
+
+	assert.Equal(t, []string{"alloc 0 (8 bytes)", "alloc 0 (8 bytes)", "release 0 (8 bytes)"}, obs.events)
+}
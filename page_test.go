@@ -0,0 +1,181 @@
+package bwarr
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pageItem struct {
+	key int64
+	seq int64 // Not compared, used to check FIFO order of equal elements.
+}
+
+func pageItemCmp(a, b pageItem) int {
+	return int(a.key - b.key)
+}
+
+type pageItemCodec struct{}
+
+func (pageItemCodec) Append(dst []byte, v pageItem) []byte {
+	dst = binary.AppendVarint(dst, v.key)
+	return binary.AppendVarint(dst, v.seq)
+}
+
+func (pageItemCodec) Decode(src []byte) (v pageItem, err error) {
+	var n, m int
+	v.key, n = binary.Varint(src)
+	if n <= 0 {
+		return v, errors.New("bad key")
+	}
+	v.seq, m = binary.Varint(src[n:])
+	if m <= 0 {
+		return v, errors.New("bad seq")
+	}
+	return v, nil
+}
+
+// fifoSorted returns model sorted by key, ascending or descending, keeping equal elements in FIFO order.
+func fifoSorted(model []pageItem, desc bool) []pageItem {
+	res := slices.Clone(model)
+	slices.SortStableFunc(res, func(a, b pageItem) int {
+		if desc {
+			return pageItemCmp(b, a)
+		}
+		return pageItemCmp(a, b)
+	})
+	return res
+}
+
+func TestBWArr_Page(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(11)) //nolint:gosec
+	bwa := New(pageItemCmp, 0)
+	var model []pageItem
+	for seq := range int64(500) {
+		v := pageItem{key: r.Int63n(20), seq: seq} // Many duplicates straddle page boundaries.
+		bwa.Insert(v)
+		model = append(model, v)
+	}
+	for range 100 {
+		k := r.Int63n(20)
+		if _, found := bwa.Delete(pageItem{key: k}); found { // Deletes the oldest one.
+			i := slices.IndexFunc(model, func(v pageItem) bool { return v.key == k })
+			model = slices.Delete(model, i, i+1)
+		}
+	}
+
+	for _, desc := range []bool{false, true} {
+		want := fifoSorted(model, desc)
+		for _, limit := range []int{1, 3, 7, 50, len(want), 1000} {
+			var got []pageItem
+			var token Token[pageItem]
+			for {
+				var items []pageItem
+				if desc {
+					items, token = bwa.PageDesc(token, limit)
+				} else {
+					items, token = bwa.Page(token, limit)
+				}
+				require.LessOrEqual(t, len(items), limit)
+				got = append(got, items...)
+				if token.IsZero() {
+					break
+				}
+				require.Len(t, items, limit)
+
+				// Round trip through the binary form, as an HTTP API would do.
+				token, _ = ParseToken(token.AppendBinary(nil, pageItemCodec{}.Append), pageItemCodec{}.Decode)
+			}
+			require.Equal(t, want, got, "desc=%v limit=%d", desc, limit)
+		}
+	}
+}
+
+func TestBWArr_PageWithInsertsBetweenPages(t *testing.T) {
+	t.Parallel()
+	bwa := New(pageItemCmp, 0)
+	for seq := range int64(6) {
+		bwa.Insert(pageItem{key: 1, seq: seq})
+	}
+	items, token := bwa.Page(Token[pageItem]{}, 4) //nolint:exhaustruct
+	assert.Equal(t, []pageItem{{1, 0}, {1, 1}, {1, 2}, {1, 3}}, items)
+
+	bwa.Insert(pageItem{key: 1, seq: 6}) // Newer equal elements go after the consumed ones.
+	bwa.Insert(pageItem{key: 0, seq: 7}) // Elements before the token position are not returned.
+	bwa.Insert(pageItem{key: 2, seq: 8})
+	items, token = bwa.Page(token, 10)
+	assert.Equal(t, []pageItem{{1, 4}, {1, 5}, {1, 6}, {2, 8}}, items)
+	assert.True(t, token.IsZero())
+}
+
+func TestBWArr_PageLargeEqualGroup(t *testing.T) {
+	t.Parallel()
+	const groupLen, limit = 10_000, 7
+	bwa := NewWithOptions(pageItemCmp, 0, Options{CountComparisons: true}) //nolint:exhaustruct
+	var model []pageItem
+	for seq := range int64(groupLen) {
+		v := pageItem{key: 1, seq: seq}
+		bwa.Insert(v)
+		model = append(model, v)
+	}
+	for range groupLen / 10 { // Deleted flags inside the group make the seek count them.
+		bwa.Delete(pageItem{key: 1}) //nolint:exhaustruct
+		model = model[1:]
+	}
+	bwa.Insert(pageItem{key: 0, seq: groupLen})
+	bwa.Insert(pageItem{key: 2, seq: groupLen + 1})
+	model = append([]pageItem{{0, groupLen}}, append(model, pageItem{2, groupLen + 1})...)
+
+	var got []pageItem
+	var token Token[pageItem]
+	maxComparisons := uint64(0)
+	for {
+		before := bwa.Stats().Comparisons
+		var items []pageItem
+		items, token = bwa.Page(token, limit)
+		maxComparisons = max(maxComparisons, bwa.Stats().Comparisons-before)
+		got = append(got, items...)
+		if token.IsZero() {
+			break
+		}
+	}
+	require.Equal(t, model, got)
+	// A page must not compare all the elements of the group, only binary search each segment.
+	assert.Less(t, maxComparisons, uint64(1000))
+}
+
+func TestBWArr_PageEmpty(t *testing.T) {
+	t.Parallel()
+	bwa := New(pageItemCmp, 0)
+	items, token := bwa.Page(Token[pageItem]{}, 10) //nolint:exhaustruct
+	assert.Empty(t, items)
+	assert.True(t, token.IsZero())
+
+	bwa.Insert(pageItem{key: 1, seq: 0})
+	items, token = bwa.PageDesc(Token[pageItem]{}, 0) //nolint:exhaustruct
+	assert.Empty(t, items)
+	assert.True(t, token.IsZero())
+
+	items, token = bwa.Page(Token[pageItem]{}, 1) //nolint:exhaustruct
+	assert.Equal(t, []pageItem{{1, 0}}, items)
+	assert.True(t, token.IsZero()) // The last page ends exactly at the last element.
+}
+
+func TestParseToken(t *testing.T) {
+	t.Parallel()
+	token, err := ParseToken[pageItem](nil, pageItemCodec{}.Decode)
+	require.NoError(t, err)
+	assert.True(t, token.IsZero())
+	assert.Empty(t, token.AppendBinary(nil, pageItemCodec{}.Append))
+
+	for _, bad := range [][]byte{{2}, {tokenVersion}, {tokenVersion, 0}, {tokenVersion, 1}} {
+		_, err = ParseToken(bad, pageItemCodec{}.Decode)
+		require.ErrorIs(t, err, ErrInvalidToken, "%v", bad)
+	}
+}
@@ -0,0 +1,76 @@
+package bwarr
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// SegmentAllocator provides memory for segments. Use NewSegmentPool to create one for Options.Pool.
+type SegmentAllocator interface {
+	segmentAllocator()
+}
+
+// SegmentPool keeps the segments released by BWArrs for reuse by other BWArrs of the same element type,
+// so workloads creating many short-lived BWArrs do not allocate segments in the steady state.
+// Pass it to NewWithOptions in Options.Pool; segments are returned to the pool by Clear(true), Compact,
+// ShrinkToFit and deletions releasing segments, so call Clear(true) when a BWArr is not needed anymore.
+// Returned segments are cleared and do not keep elements reachable.
+//
+// A SegmentPool is safe for concurrent use by BWArrs in different goroutines.
+// Passing it to a BWArr of another element type panics.
+type SegmentPool[T any] struct {
+	mu         sync.Mutex
+	free       [bits.UintSize][]segment[T] // Per-rank free lists.
+	maxPerRank int
+}
+
+// NewSegmentPool creates a SegmentPool that keeps up to maxPerRank free segments of every rank,
+// releasing the excess to the garbage collector. Zero or negative maxPerRank means no limit.
+func NewSegmentPool[T any](maxPerRank int) *SegmentPool[T] {
+	return &SegmentPool[T]{maxPerRank: maxPerRank} //nolint:exhaustruct
+}
+
+func (*SegmentPool[T]) segmentAllocator() {}
+
+// Len returns the number of free segments in the pool.
+func (p *SegmentPool[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for rank := range p.free {
+		n += len(p.free[rank])
+	}
+	return n
+}
+
+// get returns a segment of rank with no deleted elements, reusing a free one if possible.
+// allocated is false if the segment was reused.
+func (p *SegmentPool[T]) get(rank int) (seg segment[T], allocated bool) {
+	p.mu.Lock()
+	if n := len(p.free[rank]); n > 0 {
+		seg = p.free[rank][n-1]
+		p.free[rank][n-1] = segment[T]{} //nolint:exhaustruct
+		p.free[rank] = p.free[rank][:n-1]
+		p.mu.Unlock()
+		return seg, false
+	}
+	p.mu.Unlock()
+	return makeSegment[T](rank), true
+}
+
+// put returns the segment of rank to the pool. kept is false if the pool is full
+// and the segment is left to the garbage collector.
+func (p *SegmentPool[T]) put(rank int, seg segment[T]) (kept bool) {
+	if len(seg.elements) == 0 {
+		return false
+	}
+	clear(seg.elements) // Do not keep user's data reachable.
+	seg.reset()
+	p.mu.Lock()
+	if p.maxPerRank <= 0 || len(p.free[rank]) < p.maxPerRank {
+		p.free[rank] = append(p.free[rank], seg)
+		kept = true
+	}
+	p.mu.Unlock()
+	return kept
+}
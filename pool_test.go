@@ -0,0 +1,98 @@
+package bwarr
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentPool_ReusesSegments(t *testing.T) {
+	pool := NewSegmentPool[int64](0)
+	opts := Options{Pool: pool} //nolint:exhaustruct
+	cycle := func() {
+		bwa := NewWithOptions(int64Cmp, 1000, opts)
+		for i := range int64(1000) {
+			bwa.Insert(i)
+		}
+		bwa.Clear(true)
+	}
+	cycle()
+	require.Equal(t, 10, pool.Len()) // Ranks 0..9 for the capacity of 1000.
+
+	// The BWArr, its extensions and its slice of segments are the only allocations.
+	assert.LessOrEqual(t, testing.AllocsPerRun(10, cycle), 3.0)
+	assert.Equal(t, 10, pool.Len())
+}
+
+func TestSegmentPool_ReleasedSegmentsAreCleared(t *testing.T) {
+	t.Parallel()
+	pool := NewSegmentPool[*int](0)
+	bwa := NewWithOptions(func(a, b *int) int { return *a - *b }, 0, Options{Pool: pool}) //nolint:exhaustruct
+	for i := range 100 {
+		bwa.Insert(&i)
+	}
+	for i := range 100 {
+		_, found := bwa.Delete(&i)
+		require.True(t, found)
+	}
+	bwa.ShrinkToFit()
+	assert.Equal(t, 0, bwa.Len())
+	require.Positive(t, pool.Len())
+	for rank := range pool.free {
+		for _, seg := range pool.free[rank] {
+			assert.Equal(t, make([]*int, len(seg.elements)), seg.elements, "rank %d", rank)
+			assert.Zero(t, seg.deletedNum)
+		}
+	}
+}
+
+func TestSegmentPool_MaxPerRank(t *testing.T) {
+	t.Parallel()
+	pool := NewSegmentPool[int64](1)
+	a := NewWithOptions(int64Cmp, 0, Options{Pool: pool}) //nolint:exhaustruct
+	b := NewWithOptions(int64Cmp, 0, Options{Pool: pool}) //nolint:exhaustruct
+	for i := range int64(3) {
+		a.Insert(i)
+		b.Insert(i)
+	}
+	c := a.Clone()
+	a.Clear(true)
+	b.Clear(true)
+	assert.Equal(t, 2, pool.Len()) // Ranks 0 and 1, one of each.
+	var got []int64
+	c.Ascend(func(v int64) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.Equal(t, []int64{0, 1, 2}, got)
+}
+
+func TestSegmentPool_Concurrent(t *testing.T) {
+	t.Parallel()
+	pool := NewSegmentPool[int64](0)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				bwa := NewWithOptions(int64Cmp, 0, Options{Pool: pool}) //nolint:exhaustruct
+				for i := range int64(100) {
+					bwa.Insert(i * int64(g))
+				}
+				assert.Equal(t, 100, bwa.Len())
+				bwa.Clear(true)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewWithOptions_PoolOfAnotherType(t *testing.T) {
+	t.Parallel()
+	assert.Panics(t, func() {
+		NewWithOptions(int64Cmp, 0, Options{Pool: NewSegmentPool[string](0)}) //nolint:exhaustruct
+	})
+}
@@ -0,0 +1,45 @@
+package bwarr
+
+// PrefixBounds returns the bounds of the range of keys that start with prefix, in byte-wise order.
+// The upper bound is the shortest key greater than all such keys: prefix with trailing 0xFF bytes
+// removed and the last byte incremented. If there is no such key, e.g., prefix is empty or consists
+// of 0xFF bytes only, the upper bound is Unbounded.
+func PrefixBounds[S ~string | ~[]byte](prefix S) (lo, hi Bound[S]) {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] != 0xFF {
+			end[i]++
+			return Included(prefix), Excluded(S(end[:i+1]))
+		}
+	}
+	return Included(prefix), Unbounded[S]()
+}
+
+// AscendPrefix calls the iterator function for each element of bwa that starts with prefix,
+// in ascending order. Iteration stops early if the iterator returns false.
+// The BWArr must be ordered byte-wise, e.g., by cmp.Compare for strings or bytes.Compare for []byte.
+func AscendPrefix[S ~string | ~[]byte](bwa *BWArr[S], prefix S, iterator IteratorFunc[S]) {
+	lo, hi := PrefixBounds(prefix)
+	bwa.AscendBounds(lo, hi, iterator)
+}
+
+// CountPrefix returns the number of elements of bwa that start with prefix. It binary searches
+// the bounds in every segment and does not visit the elements. See AscendPrefix for the ordering requirement.
+func CountPrefix[S ~string | ~[]byte](bwa *BWArr[S], prefix S) int {
+	lo, hi := PrefixBounds(prefix)
+	return bwa.CountBounds(lo, hi)
+}
+
+// AscendKeyPrefix calls the iterator function for each element of bk whose key starts with prefix,
+// in ascending order. Iteration stops early if the iterator returns false.
+func AscendKeyPrefix[T any, K ~string](bk *ByKey[T, K], prefix K, iterator IteratorFunc[T]) {
+	lo, hi := PrefixBounds(prefix)
+	bk.AscendKeyBounds(lo, hi, iterator)
+}
+
+// CountKeyPrefix returns the number of elements of bk whose key starts with prefix.
+func CountKeyPrefix[T any, K ~string](bk *ByKey[T, K], prefix K) int {
+	lo, hi := PrefixBounds(prefix)
+	return bk.CountKeyBounds(lo, hi)
+}
@@ -0,0 +1,92 @@
+package bwarr
+
+import (
+	"bytes"
+	"cmp"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixBounds(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		prefix string
+		hi     Bound[string]
+	}{
+		{prefix: "/users/42/", hi: Excluded("/users/420")},
+		{prefix: "a", hi: Excluded("b")},
+		{prefix: "a\xff", hi: Excluded("b")},
+		{prefix: "a\xfe\xff\xff", hi: Excluded("a\xff")},
+		{prefix: "\xff\xff", hi: Unbounded[string]()},
+		{prefix: "", hi: Unbounded[string]()},
+	}
+	for _, tt := range tests {
+		lo, hi := PrefixBounds(tt.prefix)
+		assert.Equal(t, Included(tt.prefix), lo, "%q", tt.prefix)
+		assert.Equal(t, tt.hi, hi, "%q", tt.prefix)
+	}
+
+	prefix := []byte("ab\xff")
+	_, hi := PrefixBounds(prefix)
+	end, _ := hi.Value()
+	assert.Equal(t, []byte("ac"), end)
+	assert.Equal(t, []byte("ab\xff"), prefix) // The prefix is not modified.
+}
+
+func randomPath(r *rand.Rand) string {
+	parts := []string{"/users/", "/users/4", "/users/42/", "/users/42\xff", "\xff", "\xff\xff/", "a"}
+	var sb strings.Builder
+	for range 1 + r.Intn(3) {
+		sb.WriteString(parts[r.Intn(len(parts))])
+	}
+	return sb.String()
+}
+
+func TestAscendPrefix(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(5)) //nolint:gosec
+	strs := New(cmp.Compare[string], 0)
+	byteSlices := New(bytes.Compare, 0)
+	paths := NewByKey(func(p []string) string { return p[0] })
+	for range 2000 {
+		p := randomPath(r)
+		strs.Insert(p)
+		byteSlices.Insert([]byte(p))
+		paths.Insert([]string{p})
+	}
+	for range 500 {
+		p := randomPath(r)
+		strs.Delete(p)
+		byteSlices.Delete([]byte(p))
+		paths.DeleteKey(p)
+	}
+
+	for _, prefix := range []string{"", "/users/", "/users/42/", "/users/42\xff", "\xff", "\xff\xff", "a", "b"} {
+		var want []string
+		strs.Ascend(func(s string) bool {
+			if strings.HasPrefix(s, prefix) {
+				want = append(want, s)
+			}
+			return true
+		})
+
+		var got []string
+		AscendPrefix(strs, prefix, func(s string) bool { got = append(got, s); return true })
+		require.Equal(t, want, got, "%q", prefix)
+		require.Equal(t, len(want), CountPrefix(strs, prefix), "%q", prefix)
+
+		got = got[:0]
+		AscendPrefix(byteSlices, []byte(prefix), func(b []byte) bool { got = append(got, string(b)); return true })
+		require.Equal(t, want, got, "%q", prefix)
+		require.Equal(t, len(want), CountPrefix(byteSlices, []byte(prefix)), "%q", prefix)
+
+		got = got[:0]
+		AscendKeyPrefix(paths, prefix, func(p []string) bool { got = append(got, p[0]); return true })
+		require.Equal(t, want, got, "%q", prefix)
+		require.Equal(t, len(want), CountKeyPrefix(paths, prefix), "%q", prefix)
+	}
+}
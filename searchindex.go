@@ -0,0 +1,139 @@
+package bwarr
+
+import (
+	"math/bits"
+	"slices"
+	"unsafe"
+)
+
+// cacheLineSize is the memory range a search index narrows the binary search in a segment to.
+const cacheLineSize = 64
+
+// searchIndexes keeps Eytzinger-ordered samples of large segments (Khuong and Morin, 2017), see
+// Options.SearchIndexMinRank. Samples are the elements at indexes step, 2*step, ... of a segment, so
+// a segment of rank r has 2^r/step-1 samples forming a complete binary search tree: descending it touches
+// a few cache lines which stay hot between searches, and finding the position among samples narrows
+// the search to a cache-line range of the segment.
+//
+// Samples are copies rebuilt when the segment is rewritten; lazy deletions keep them valid.
+type searchIndexes[T any] struct {
+	minRank int
+	step    int   // Power of two, at least 2.
+	trees   [][]T // Per-rank samples in Eytzinger order, 1-based; nil below minRank and for inactive segments.
+}
+
+func newSearchIndexes[T any](minRank int) *searchIndexes[T] {
+	var zero T
+	step := 2
+	if size := int(unsafe.Sizeof(zero)); size > 0 && size < cacheLineSize/2 {
+		step = 1 << (bits.Len(uint(cacheLineSize/size)) - 1)
+	}
+	// The tree needs at least one sample.
+	return &searchIndexes[T]{minRank: max(minRank, bits.Len(uint(step))), step: step, trees: nil}
+}
+
+func (si *searchIndexes[T]) clone() *searchIndexes[T] {
+	c := &searchIndexes[T]{minRank: si.minRank, step: si.step, trees: make([][]T, len(si.trees))}
+	for rank, tree := range si.trees {
+		c.trees[rank] = slices.Clone(tree)
+	}
+	return c
+}
+
+func (si *searchIndexes[T]) allocatedBytes() int {
+	var zero T
+	n := 0
+	for _, tree := range si.trees {
+		n += cap(tree) * int(unsafe.Sizeof(zero))
+	}
+	return n
+}
+
+// build rebuilds the index of the segment of rank after the segment was rewritten.
+func (si *searchIndexes[T]) build(rank int, seg *segment[T]) {
+	if rank < si.minRank {
+		return
+	}
+	if rank >= len(si.trees) {
+		si.trees = append(si.trees, make([][]T, rank-len(si.trees)+1)...)
+	}
+	samples := len(seg.elements)/si.step - 1
+	tree := si.trees[rank]
+	if len(tree) != samples+1 {
+		tree = make([]T, samples+1)
+		si.trees[rank] = tree
+	}
+	// In-order traversal of the tree visits the samples in sorted order.
+	next := si.step
+	var fill func(k int)
+	fill = func(k int) {
+		if k > samples {
+			return
+		}
+		fill(2 * k)
+		tree[k] = seg.elements[next]
+		next += si.step
+		fill(2*k + 1)
+	}
+	fill(1)
+}
+
+// release drops the index of the released segment of rank.
+func (si *searchIndexes[T]) release(rank int) {
+	if rank < len(si.trees) {
+		si.trees[rank] = nil
+	}
+}
+
+// narrow returns [lo, hi) such that elements of the segment of rank before lo are less than val and
+// elements at hi and after are greater. It returns false if the segment has no index.
+func (si *searchIndexes[T]) narrow(cmp CmpFunc[T], rank int, seg *segment[T], val T) (lo, hi int, ok bool) {
+	if rank >= len(si.trees) || si.trees[rank] == nil {
+		return 0, 0, false
+	}
+	tree := si.trees[rank]
+	samples := len(tree) - 1
+	less := si.countSamples(cmp, tree, val, false)
+	lo = less*si.step + 1 // The sample at less*step is less than val.
+	if less == 0 {
+		lo = 0
+	}
+	// Usually the next sample is greater than val; otherwise there are equal samples to skip.
+	notGreater := less
+	if less < samples && cmp(seg.elements[(less+1)*si.step], val) <= 0 {
+		notGreater = si.countSamples(cmp, tree, val, true)
+	}
+	if notGreater == samples {
+		return lo, len(seg.elements), true
+	}
+	return lo, (notGreater + 1) * si.step, true
+}
+
+// countSamples returns the number of samples less than val, or less or equal if orEqual is set.
+func (si *searchIndexes[T]) countSamples(cmp CmpFunc[T], tree []T, val T, orEqual bool) int {
+	samples := len(tree) - 1
+	k := 1
+	for k <= samples {
+		c := cmp(tree[k], val)
+		if c < 0 || (orEqual && c == 0) {
+			k = 2*k + 1
+		} else {
+			k = 2 * k
+		}
+	}
+	// The tree is complete, so every descent takes the same number of steps, and the leaf position
+	// below the last level is the in-order position of val among the samples.
+	return k - (samples + 1)
+}
+
+// indexRange returns the range of non-deleted elements of s to search for element in,
+// narrowed by the search index. It returns false if s has no index.
+func (bwa *BWArr[T]) indexRange(s *segment[T], element T) (b, e int, ok bool) {
+	rank := bits.TrailingZeros(uint(len(s.elements)))
+	lo, hi, ok := bwa.ext.index.narrow(bwa.cmp, rank, s, element)
+	if !ok {
+		return 0, 0, false
+	}
+	e = min(hi, s.maxNonDeletedIdx+1)
+	return min(max(lo, s.minNonDeletedIdx), e), e, true
+}
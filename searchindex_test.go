@@ -0,0 +1,55 @@
+package bwarr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromSliceWithOptions_SearchIndex(t *testing.T) {
+	t.Parallel()
+	slice := make([]int64, 3000)
+	for i := range slice {
+		slice[i] = int64(i / 3 * 2) // Three equal elements in a row.
+	}
+	bwa := NewFromSliceWithOptions(int64Cmp, slice, Options{SearchIndexMinRank: 4}) //nolint:exhaustruct
+	for i := range int64(2000) {
+		assert.Equal(t, i%2 == 0, bwa.Has(i), "%d", i)
+	}
+	assert.Equal(t, 3, bwa.CountBounds(Included[int64](100), Included[int64](100)))
+	assert.Greater(t, bwa.Stats().BytesAllocated, NewFromSlice(int64Cmp, slice).Stats().BytesAllocated)
+
+	bwa.Clear(true)
+	bwa.Insert(1)
+	assert.True(t, bwa.Has(1))
+}
+
+func Test_searchIndexes_narrow(t *testing.T) {
+	t.Parallel()
+	si := newSearchIndexes[int64](0)
+	require.Equal(t, 8, si.step)
+	seg := makeSegment[int64](6)
+	for i := range seg.elements {
+		seg.elements[i] = int64(i / 4) // Runs of four equal elements.
+	}
+	si.build(6, &seg)
+
+	for val := int64(-1); val <= 17; val++ {
+		lo, hi, ok := si.narrow(int64Cmp, 6, &seg, val)
+		require.True(t, ok)
+		require.LessOrEqual(t, lo, hi)
+		for i, el := range seg.elements {
+			if i < lo {
+				assert.Less(t, el, val, "val %d index %d", val, i)
+			}
+			if i >= hi {
+				assert.Greater(t, el, val, "val %d index %d", val, i)
+			}
+		}
+		assert.LessOrEqual(t, hi-lo, 2*si.step, "val %d", val)
+	}
+
+	_, _, ok := si.narrow(int64Cmp, 5, &seg, 1)
+	assert.False(t, ok)
+}
@@ -3,41 +3,109 @@ package bwarr
 import (
 	"math"
 	"math/bits"
+	"unsafe"
 )
 
 type segment[T any] struct {
-	elements         []T    // Stores user's data.
-	deleted          []bool // Stores whether i-th element is deleted.
-	deletedNum       int    // Number of deleted elements in the segment.
-	minNonDeletedIdx int    // Index of the first non-deleted element in the segment.
-	maxNonDeletedIdx int    // Index of the last non-deleted element in the segment.
+	elements         []T      // Stores user's data.
+	deleted          []uint64 // Bitmap: i-th bit is set if i-th element is deleted. Nil until the first deletion.
+	deletedNum       int      // Number of deleted elements in the segment.
+	minNonDeletedIdx int      // Index of the first non-deleted element in the segment.
+	maxNonDeletedIdx int      // Index of the last non-deleted element in the segment.
 }
 
-func createSegments[T any](fromRank, toRank int) []segment[T] {
-	segments := make([]segment[T], toRank-fromRank)
-	for i := fromRank; i < toRank; i++ {
-		segments[i-fromRank] = makeSegment[T](i)
+const wordBits = 64
+
+// newDeletedBitmap returns a bitmap of deletion flags for n elements, all elements are non-deleted.
+func newDeletedBitmap(n int) []uint64 {
+	return make([]uint64, (n+wordBits-1)/wordBits)
+}
+
+// isDeletedBit returns the i-th flag of the bitmap. Nil bitmap has all flags unset.
+func isDeletedBit(bitmap []uint64, i int) bool {
+	return bitmap != nil && bitmap[i/wordBits]&(1<<(uint(i)%wordBits)) != 0 //nolint:gosec
+}
+
+func setDeletedBit(bitmap []uint64, i int, deleted bool) {
+	mask := uint64(1) << (uint(i) % wordBits) //nolint:gosec
+	if deleted {
+		bitmap[i/wordBits] |= mask
+	} else {
+		bitmap[i/wordBits] &^= mask
 	}
-	return segments
+}
+
+// copyDeletedBits copies n flags from src starting at srcIdx to dst starting at dstIdx, a word of dst at once.
+// Nil src has all flags unset. Overlapping ranges are allowed if dstIdx <= srcIdx: every word of src
+// is read before it is overwritten.
+func copyDeletedBits(dst []uint64, dstIdx int, src []uint64, srcIdx, n int) {
+	for n > 0 {
+		off := uint(dstIdx) % wordBits //nolint:gosec
+		k := min(n, wordBits-int(off))
+		mask := ^uint64(0) >> (wordBits - k) << off
+		dst[dstIdx/wordBits] = dst[dstIdx/wordBits]&^mask | readDeletedBits(src, srcIdx, k)<<off
+		dstIdx, srcIdx, n = dstIdx+k, srcIdx+k, n-k
+	}
+}
+
+// readDeletedBits returns k <= 64 flags of the bitmap starting at i in the lowest bits of the result.
+func readDeletedBits(bitmap []uint64, i, k int) uint64 {
+	if bitmap == nil {
+		return 0
+	}
+	w, off := i/wordBits, uint(i)%wordBits //nolint:gosec
+	word := bitmap[w] >> off
+	if int(off)+k > wordBits {
+		word |= bitmap[w+1] << (wordBits - off)
+	}
+	return word & (^uint64(0) >> (wordBits - k))
 }
 
 func makeSegment[T any](rank int) segment[T] {
 	l := 1 << rank
 	return segment[T]{
 		elements:         make([]T, l),
-		deleted:          make([]bool, l),
+		deleted:          nil,
 		deletedNum:       0,
 		minNonDeletedIdx: 0,
 		maxNonDeletedIdx: l - 1,
 	}
 }
 
+// allocatedBytes returns the memory held by the segment's elements and deletion flags.
+func (s *segment[T]) allocatedBytes() int {
+	return cap(s.elements)*int(unsafe.Sizeof(*new(T))) + cap(s.deleted)*int(unsafe.Sizeof(uint64(0)))
+}
+
+// markDeleted sets the deleted flag of the i-th element, allocating the bitmap on the first deletion.
+func (s *segment[T]) markDeleted(i int) {
+	s.ensureDeletedBitmap()
+	setDeletedBit(s.deleted, i, true)
+}
+
+// ensureDeletedBitmap allocates the bitmap of a segment that has not had deleted elements yet.
+func (s *segment[T]) ensureDeletedBitmap() {
+	if s.deleted == nil {
+		s.deleted = newDeletedBitmap(len(s.elements))
+	}
+}
+
+// reset marks all elements of the segment as non-deleted. Inactive segments may keep
+// stale deletion marks, so it is used when such segment becomes active again.
+func (s *segment[T]) reset() {
+	clear(s.deleted)
+	s.deletedNum = 0
+	s.minNonDeletedIdx, s.maxNonDeletedIdx = 0, len(s.elements)-1
+}
+
 // Merge lowSeg and highSeg into highSeg using highSeg free space at the beginning.
 func mergeSegments[T any](lowSeg, highSeg *segment[T], cmp CmpFunc[T], highSegReadIdx int) {
 	if lowSeg.deletedNum == 0 && highSeg.deletedNum == 0 {
 		mergeSegmentsClean(lowSeg, highSeg, cmp, highSegReadIdx)
 	} else {
 		mergeSegmentsDirty(lowSeg, highSeg, cmp, highSegReadIdx)
+		highSeg.updateNonDeletedBounds()
+		return
 	}
 
 	highSeg.minNonDeletedIdx = 0
@@ -77,124 +145,118 @@ func mergeSegmentsDirty[T any](lowSeg, highSeg *segment[T], cmp CmpFunc[T], high
 	highSegWriteIdx := highSegReadIdx - lowSegEnd
 	highSegEnd := highSegReadIdx + lowSegEnd
 
+	highSeg.ensureDeletedBitmap()
 	// Sub-slice so the compiler can prove loop indices are in bounds (BCE).
 	highElems := highSeg.elements[:highSegEnd]
-	highDel := highSeg.deleted[:highSegEnd]
+	highDel := highSeg.deleted
 	lowElems := lowSeg.elements[:lowSegEnd]
-	lowDel := lowSeg.deleted[:lowSegEnd]
+	lowDel := lowSeg.deleted
 
 	lowSegReadIdx := 0
 
 	for highSegReadIdx < len(highElems) && lowSegReadIdx < len(lowElems) {
 		cmpResult := cmp(highElems[highSegReadIdx], lowElems[lowSegReadIdx])
-		if (cmpResult < 0) || (cmpResult == 0 && !highDel[highSegReadIdx]) {
+		if (cmpResult < 0) || (cmpResult == 0 && !isDeletedBit(highDel, highSegReadIdx)) {
 			highElems[highSegWriteIdx] = highElems[highSegReadIdx]
-			highDel[highSegWriteIdx] = highDel[highSegReadIdx]
+			setDeletedBit(highDel, highSegWriteIdx, isDeletedBit(highDel, highSegReadIdx))
 			highSegReadIdx++
 		} else {
 			highElems[highSegWriteIdx] = lowElems[lowSegReadIdx]
-			highDel[highSegWriteIdx] = lowDel[lowSegReadIdx]
+			setDeletedBit(highDel, highSegWriteIdx, isDeletedBit(lowDel, lowSegReadIdx))
 			lowSegReadIdx++
 		}
 		highSegWriteIdx++
 	}
 
 	copy(highSeg.elements[highSegWriteIdx:], highSeg.elements[highSegReadIdx:highSegEnd])
-	copy(highSeg.deleted[highSegWriteIdx:], highSeg.deleted[highSegReadIdx:highSegEnd])
+	copyDeletedBits(highDel, highSegWriteIdx, highDel, highSegReadIdx, highSegEnd-highSegReadIdx)
+	highSegWriteIdx += highSegEnd - highSegReadIdx
 	copy(highSeg.elements[highSegWriteIdx:], lowSeg.elements[lowSegReadIdx:lowSegEnd])
-	copy(highSeg.deleted[highSegWriteIdx:], lowSeg.deleted[lowSegReadIdx:lowSegEnd])
+	copyDeletedBits(highDel, highSegWriteIdx, lowDel, lowSegReadIdx, lowSegEnd-lowSegReadIdx)
 
 	highSeg.deletedNum += lowSeg.deletedNum
 }
 
 // Merge lowSeg and highSeg into highSeg using highSeg free space at the beginning.
-// Preserve FIFO order for deleting. Maintain min/max non-deleted indexes.
+// Preserve FIFO order for deleting. Maintain exact min/max non-deleted indexes.
 func mergeSegmentsForDel[T any](lowSeg, highSeg *segment[T], cmp CmpFunc[T], highSegReadIdx int) {
 	lowSegEnd := len(lowSeg.elements)
 	highSegWriteIdx := highSegReadIdx - lowSegEnd
 	highSegEnd := highSegReadIdx + lowSegEnd
 
+	highSeg.ensureDeletedBitmap()
 	// Sub-slice so the compiler can prove loop indices are in bounds (BCE).
 	highElems := highSeg.elements[:highSegEnd]
-	highDel := highSeg.deleted[:highSegEnd]
+	highDel := highSeg.deleted
 	lowElems := lowSeg.elements[:lowSegEnd]
-	lowDel := lowSeg.deleted[:lowSegEnd]
+	lowDel := lowSeg.deleted
 
 	lowSegReadIdx := 0
 
 	for highSegReadIdx < len(highElems) && lowSegReadIdx < len(lowElems) {
-		var del bool
 		cmpResult := cmp(highElems[highSegReadIdx], lowElems[lowSegReadIdx])
-		if (cmpResult > 0) || (cmpResult == 0 && !lowDel[lowSegReadIdx]) {
+		if (cmpResult > 0) || (cmpResult == 0 && !isDeletedBit(lowDel, lowSegReadIdx)) {
 			highElems[highSegWriteIdx] = lowElems[lowSegReadIdx]
-			del = lowDel[lowSegReadIdx]
-			highDel[highSegWriteIdx] = del
+			setDeletedBit(highDel, highSegWriteIdx, isDeletedBit(lowDel, lowSegReadIdx))
 			lowSegReadIdx++
 		} else {
 			highElems[highSegWriteIdx] = highElems[highSegReadIdx]
-			del = highDel[highSegReadIdx]
-			highDel[highSegWriteIdx] = del
+			setDeletedBit(highDel, highSegWriteIdx, isDeletedBit(highDel, highSegReadIdx))
 			highSegReadIdx++
 		}
-		if !del {
-			highSeg.maxNonDeletedIdx = max(highSeg.maxNonDeletedIdx, highSegWriteIdx)
-			highSeg.minNonDeletedIdx = min(highSeg.minNonDeletedIdx, highSegWriteIdx)
-		}
 		highSegWriteIdx++
 	}
 
-	for highSegReadIdx < len(highElems) {
-		highElems[highSegWriteIdx] = highElems[highSegReadIdx]
-		del := highDel[highSegReadIdx]
-		highDel[highSegWriteIdx] = del
-		if !del {
-			highSeg.maxNonDeletedIdx = max(highSeg.maxNonDeletedIdx, highSegWriteIdx)
-			highSeg.minNonDeletedIdx = min(highSeg.minNonDeletedIdx, highSegWriteIdx)
-		}
-		highSegWriteIdx++
-		highSegReadIdx++
-	}
-	for lowSegReadIdx < len(lowElems) {
-		highElems[highSegWriteIdx] = lowElems[lowSegReadIdx]
-		del := lowDel[lowSegReadIdx]
-		highDel[highSegWriteIdx] = del
-		if !del {
-			highSeg.maxNonDeletedIdx = max(highSeg.maxNonDeletedIdx, highSegWriteIdx)
-			highSeg.minNonDeletedIdx = min(highSeg.minNonDeletedIdx, highSegWriteIdx)
-		}
-		highSegWriteIdx++
-		lowSegReadIdx++
-	}
+	copy(highSeg.elements[highSegWriteIdx:], highSeg.elements[highSegReadIdx:highSegEnd])
+	copyDeletedBits(highDel, highSegWriteIdx, highDel, highSegReadIdx, highSegEnd-highSegReadIdx)
+	highSegWriteIdx += highSegEnd - highSegReadIdx
+	copy(highSeg.elements[highSegWriteIdx:], lowSeg.elements[lowSegReadIdx:lowSegEnd])
+	copyDeletedBits(highDel, highSegWriteIdx, lowDel, lowSegReadIdx, lowSegEnd-lowSegReadIdx)
 
 	highSeg.deletedNum += lowSeg.deletedNum
+	highSeg.updateNonDeletedBounds()
 }
 
 func demoteSegment[T any](from segment[T], to *segment[T]) {
-	for r, w := 0, 0; r < len(from.elements); r++ {
-		if from.deleted[r] {
-			continue
-		}
+	l := len(from.elements)
+	for r, w := from.nextNonDeletedAfter(-1), 0; r < l; r, w = from.nextNonDeletedAfter(r), w+1 {
 		to.elements[w] = from.elements[r]
-		to.deleted[w] = false
-		w++
 	}
+	clear(to.deleted)
 	to.deletedNum = 0 // Since demoteSegment is called only when we have exact len(to.elements) undeleted elements in from.
 	to.minNonDeletedIdx, to.maxNonDeletedIdx = 0, len(to.elements)-1
 }
 
+// mergeLive appends to dst the sorted merge of newer and the non-deleted elements of the older segment.
+// Equal elements from newer go first.
+func mergeLive[T any](dst, newer []T, older *segment[T], cmp CmpFunc[T]) []T {
+	l := len(older.elements)
+	i, j := 0, older.nextNonDeletedAfter(-1)
+	for i < len(newer) && j < l {
+		if cmp(older.elements[j], newer[i]) < 0 {
+			dst = append(dst, older.elements[j])
+			j = older.nextNonDeletedAfter(j)
+		} else {
+			dst = append(dst, newer[i])
+			i++
+		}
+	}
+	dst = append(dst, newer[i:]...)
+	for ; j < l; j = older.nextNonDeletedAfter(j) {
+		dst = append(dst, older.elements[j])
+	}
+	return dst
+}
+
 // moveNonDeletedValuesToSegmentEnd moves all non-deleted values to the end of the segment, preserving their order.
 // It is used when a half of the elements in the segment deleted, as preparation for merging with lower segment.
 func moveNonDeletedValuesToSegmentEnd[T any](seg segment[T]) {
 	length := len(seg.elements)
 	writePointer := length - 1
-	readPointer := length - 1
-	for writePointer >= (length >> 1) {
-		if !seg.deleted[readPointer] {
-			seg.elements[writePointer] = seg.elements[readPointer]
-			seg.deleted[writePointer] = false
-			writePointer--
-		}
-		readPointer--
+	for readPointer := seg.prevNonDeletedBefore(length); writePointer >= (length >> 1); readPointer = seg.prevNonDeletedBefore(readPointer) {
+		seg.elements[writePointer] = seg.elements[readPointer]
+		setDeletedBit(seg.deleted, writePointer, false)
+		writePointer--
 	}
 	seg.deletedNum = length >> 1
 	seg.minNonDeletedIdx, seg.maxNonDeletedIdx = length>>1, length-1
@@ -202,11 +264,20 @@ func moveNonDeletedValuesToSegmentEnd[T any](seg segment[T]) {
 
 // returns index of the rightmost element equal to val that is not deleted.
 func (s *segment[T]) findRightmostNotDeleted(cmp CmpFunc[T], val T) int {
+	return s.findRightmostNotDeletedIn(cmp, val, s.minNonDeletedIdx, s.maxNonDeletedIdx+1)
+}
+
+// findRightmostNotDeletedIn is findRightmostNotDeleted that searches in [b, e) only.
+// Elements before b must be less than val, elements at e and after must be greater or deleted.
+func (s *segment[T]) findRightmostNotDeletedIn(cmp CmpFunc[T], val T, b, e int) int {
+	return searchRightmostNotDeleted(s, cmp, val, b, e)
+}
+
+// searchRightmostNotDeleted is findRightmostNotDeletedIn for probes of any type P, cmp compares
+// the probe with an element. It lets ByKey search by key with the same code.
+func searchRightmostNotDeleted[T, P any](s *segment[T], cmp func(P, T) int, val P, b, e int) int {
 	// Sub-slice for BCE: the compiler tracks len(elems) through e's mutations.
-	elems := s.elements[:s.maxNonDeletedIdx+1]
-	del := s.deleted[:s.maxNonDeletedIdx+1]
-	b := s.minNonDeletedIdx
-	e := len(elems)
+	elems := s.elements[:e]
 	for b < e {
 		m := (b + e) >> 1
 		cmpRes := cmp(val, elems[m])
@@ -216,7 +287,7 @@ func (s *segment[T]) findRightmostNotDeleted(cmp CmpFunc[T], val T) int {
 		case cmpRes > 0:
 			b = m + 1
 		default: // elements are equal - follow invariant: deleted elements are to the right (higher index) of non-deleted ones.
-			if del[m] {
+			if isDeletedBit(s.deleted, m) {
 				e = m
 			} else {
 				b = m + 1
@@ -229,10 +300,10 @@ func (s *segment[T]) findRightmostNotDeleted(cmp CmpFunc[T], val T) int {
 		return -1
 	}
 	idx--
-	if del[idx] {
+	if isDeletedBit(s.deleted, idx) {
 		return -1
 	}
-	if cmp(elems[idx], val) != 0 {
+	if cmp(val, elems[idx]) != 0 {
 		return -1
 	}
 	return idx
@@ -243,7 +314,7 @@ func (s *segment[T]) findRightmostNotDeleted(cmp CmpFunc[T], val T) int {
 func (s *segment[T]) min(cmp CmpFunc[T]) int {
 	minIdx, maxIdx := s.minNonDeletedIndex(), s.maxNonDeletedIndex()
 	for i := minIdx + 1; i <= maxIdx; i++ {
-		if s.deleted[i] { // deleted elements can appear only after non-deleted equal ones;
+		if isDeletedBit(s.deleted, i) { // deleted elements can appear only after non-deleted equal ones;
 			return minIdx
 		}
 		if cmp(s.elements[i], s.elements[minIdx]) != 0 {
@@ -257,13 +328,46 @@ func (s *segment[T]) min(cmp CmpFunc[T]) int {
 // returns index of the first element that is greater or equal to val and is not deleted.
 // If all elements are less than val, returns -1.
 func (s *segment[T]) findGTOE(cmp CmpFunc[T], val T) int {
-	elems := s.elements[:s.maxNonDeletedIdx+1]
-	b := s.minNonDeletedIdx
-	e := len(elems)
+	return s.findFirstGreater(cmp, val, true)
+}
+
+// returns index of the first element that is greater than val and is not deleted.
+// If all elements are less or equal to val, returns -1.
+func (s *segment[T]) findGT(cmp CmpFunc[T], val T) int {
+	return s.findFirstGreater(cmp, val, false)
+}
+
+// returns index of the last element that is less than val and is not deleted.
+// If all elements are greater or equal to val, returns -1.
+func (s *segment[T]) findLess(cmp CmpFunc[T], val T) int {
+	return s.findLastLess(cmp, val, false)
+}
+
+// returns index of the last element that is less or equal to val and is not deleted.
+// If all elements are greater than val, returns -1.
+func (s *segment[T]) findLTOE(cmp CmpFunc[T], val T) int {
+	return s.findLastLess(cmp, val, true)
+}
+
+// findFirstGreater returns index of the first non-deleted element that is greater than val,
+// or greater or equal if orEqual is set. If there is no such element, returns -1.
+func (s *segment[T]) findFirstGreater(cmp CmpFunc[T], val T, orEqual bool) int {
+	return s.findFirstGreaterIn(cmp, val, orEqual, s.minNonDeletedIdx, s.maxNonDeletedIdx+1)
+}
+
+// findFirstGreaterIn is findFirstGreater that searches in [b, e) only. Elements before b must not match,
+// elements at e and after must match or be deleted.
+func (s *segment[T]) findFirstGreaterIn(cmp CmpFunc[T], val T, orEqual bool, b, e int) int {
+	return searchFirstGreater(s, cmp, val, orEqual, b, e)
+}
+
+// searchFirstGreater is findFirstGreaterIn for probes of any type P, see searchRightmostNotDeleted.
+func searchFirstGreater[T, P any](s *segment[T], cmp func(P, T) int, val P, orEqual bool, b, e int) int {
+	elems := s.elements[:e]
 	for b < e {
 		m := (b + e) >> 1
 		cmpRes := cmp(val, elems[m])
-		if cmpRes <= 0 {
+		if cmpRes < 0 || (orEqual && cmpRes == 0) {
 			e = m
 		} else {
 			b = m + 1
@@ -275,15 +379,26 @@ func (s *segment[T]) findGTOE(cmp CmpFunc[T], val T) int {
 	return s.nextNonDeletedAfter(b - 1)
 }
 
-// returns index of the first element that is less than val and is not deleted.
-// If all elements are greater or equal to val, returns -1.
-func (s *segment[T]) findLess(cmp CmpFunc[T], val T) int {
-	elems := s.elements[:s.maxNonDeletedIdx+1]
-	b, e := s.minNonDeletedIdx-1, s.maxNonDeletedIdx
+// findLastLess returns index of the last non-deleted element that is less than val,
+// or less or equal if orEqual is set. If there is no such element, returns -1.
+func (s *segment[T]) findLastLess(cmp CmpFunc[T], val T, orEqual bool) int {
+	return s.findLastLessIn(cmp, val, orEqual, s.minNonDeletedIdx, s.maxNonDeletedIdx+1)
+}
+
+// findLastLessIn is findLastLess that searches in [b, e) only. Elements before b must match or be deleted,
+// elements at e and after must not match.
+func (s *segment[T]) findLastLessIn(cmp CmpFunc[T], val T, orEqual bool, b, e int) int {
+	return searchLastLess(s, cmp, val, orEqual, b, e)
+}
+
+// searchLastLess is findLastLessIn for probes of any type P, see searchRightmostNotDeleted.
+func searchLastLess[T, P any](s *segment[T], cmp func(P, T) int, val P, orEqual bool, b, e int) int {
+	elems := s.elements[:e]
+	b, e = b-1, e-1
 	for b < e {
 		m := (b+e)>>1 + 1
 		cmpRes := cmp(val, elems[m])
-		if cmpRes > 0 {
+		if cmpRes > 0 || (orEqual && cmpRes == 0) {
 			b = m
 		} else {
 			e = m - 1
@@ -292,41 +407,107 @@ func (s *segment[T]) findLess(cmp CmpFunc[T], val T) int {
 	return s.prevNonDeletedBefore(e + 1)
 }
 
-func (s *segment[T]) minNonDeletedIndex() (index int) {
-	for i := s.minNonDeletedIdx; i < len(s.deleted); i++ {
-		if !s.deleted[i] {
-			s.minNonDeletedIdx = i
-			return i
+// countNonDeleted returns the number of non-deleted elements with indexes in [first, last].
+func (s *segment[T]) countNonDeleted(first, last int) int {
+	if s.deleted == nil {
+		return last - first + 1
+	}
+	deleted := 0
+	firstWord, lastWord := first/wordBits, last/wordBits
+	for w := firstWord; w <= lastWord; w++ {
+		word := s.deleted[w]
+		if w == firstWord {
+			word &= ^uint64(0) << (uint(first) % wordBits) //nolint:gosec
+		}
+		if w == lastWord {
+			word &= ^uint64(0) >> (wordBits - 1 - uint(last)%wordBits) //nolint:gosec
 		}
+		deleted += bits.OnesCount64(word)
 	}
-	return -1
+	return last - first + 1 - deleted
 }
 
-func (s *segment[T]) maxNonDeletedIndex() (index int) {
-	for i := s.maxNonDeletedIdx; i >= 0; i-- {
-		if !s.deleted[i] {
-			s.maxNonDeletedIdx = i
-			return i
-		}
+// updateNonDeletedBounds sets minNonDeletedIdx and maxNonDeletedIdx to the indexes of the first and the last
+// non-deleted elements. Read operations rely on the bounds being exact, since they never update them.
+func (s *segment[T]) updateNonDeletedBounds() {
+	s.minNonDeletedIdx, s.maxNonDeletedIdx = s.nextNonDeletedAfter(-1), s.prevNonDeletedBefore(len(s.elements))
+}
+
+// minNonDeletedIndex returns the index of the first non-deleted element, or -1 if there is none.
+// It does not modify the segment, so it is safe for concurrent reads.
+func (s *segment[T]) minNonDeletedIndex() (index int) {
+	i := s.nextNonDeletedAfter(s.minNonDeletedIdx - 1)
+	if i >= len(s.elements) {
+		return -1
 	}
-	return -1
+	return i
+}
+
+// maxNonDeletedIndex returns the index of the last non-deleted element, or -1 if there is none.
+// It does not modify the segment, so it is safe for concurrent reads.
+func (s *segment[T]) maxNonDeletedIndex() (index int) {
+	return s.prevNonDeletedBefore(s.maxNonDeletedIdx + 1)
 }
 
+// nextNonDeletedAfter returns the index of the first non-deleted element after index,
+// or the length of the segment if there is no such element. Skips a word of deleted flags at once.
 func (s *segment[T]) nextNonDeletedAfter(index int) int {
-	l := len(s.deleted)
-	for i := index + 1; i < l; i++ {
-		if !s.deleted[i] {
-			return i
+	l := len(s.elements)
+	i := index + 1
+	if i >= l || s.deleted == nil {
+		return min(i, l)
+	}
+	w := i / wordBits
+	if nonDel := ^s.deleted[w] >> (uint(i) % wordBits); nonDel != 0 { //nolint:gosec
+		return min(i+bits.TrailingZeros64(nonDel), l)
+	}
+	for w++; w < len(s.deleted); w++ {
+		if nonDel := ^s.deleted[w]; nonDel != 0 {
+			return min(w*wordBits+bits.TrailingZeros64(nonDel), l)
 		}
 	}
 	return l
 }
 
+// prevNonDeletedBefore returns the index of the last non-deleted element before index,
+// or -1 if there is no such element. Skips a word of deleted flags at once.
 func (s *segment[T]) prevNonDeletedBefore(index int) int {
-	for i := index - 1; i >= 0; i-- {
-		if !s.deleted[i] {
-			return i
+	i := min(index, len(s.elements)) - 1
+	if i < 0 || s.deleted == nil {
+		return max(i, -1)
+	}
+	w := i / wordBits
+	if nonDel := ^s.deleted[w] << (wordBits - 1 - uint(i)%wordBits); nonDel != 0 { //nolint:gosec
+		return i - bits.LeadingZeros64(nonDel)
+	}
+	for w--; w >= 0; w-- {
+		if nonDel := ^s.deleted[w]; nonDel != 0 {
+			return w*wordBits + wordBits - 1 - bits.LeadingZeros64(nonDel)
+		}
+	}
+	return -1
+}
+
+// nthNonDeletedDownFrom returns the index of the n-th (counting from zero) non-deleted element going down
+// from index last inclusive, or -1 if there are not enough of them. Counts a word of deleted flags at once.
+func (s *segment[T]) nthNonDeletedDownFrom(last, n int) int {
+	if s.deleted == nil {
+		return max(last-n, -1)
+	}
+	lastWord := last / wordBits
+	for w := lastWord; w >= 0; w-- {
+		nonDel := ^s.deleted[w]
+		if w == lastWord {
+			nonDel &= ^uint64(0) >> (wordBits - 1 - uint(last)%wordBits) //nolint:gosec
+		}
+		if c := bits.OnesCount64(nonDel); c <= n {
+			n -= c
+			continue
+		}
+		for ; n > 0; n-- { // Drop the n highest non-deleted elements of the word.
+			nonDel &^= 1 << (wordBits - 1 - bits.LeadingZeros64(nonDel))
 		}
+		return w*wordBits + wordBits - 1 - bits.LeadingZeros64(nonDel)
 	}
 	return -1
 }
@@ -334,16 +515,27 @@ func (s *segment[T]) prevNonDeletedBefore(index int) int {
 func (s *segment[T]) deepCopy() segment[T] {
 	newSeg := segment[T]{
 		elements:         make([]T, len(s.elements)),
-		deleted:          make([]bool, len(s.deleted)),
-		deletedNum:       s.deletedNum,
-		minNonDeletedIdx: s.minNonDeletedIdx,
-		maxNonDeletedIdx: s.maxNonDeletedIdx,
+		deleted:          nil,
+		deletedNum:       0,
+		minNonDeletedIdx: 0,
+		maxNonDeletedIdx: 0,
 	}
-	copy(newSeg.elements, s.elements)
-	copy(newSeg.deleted, s.deleted)
+	newSeg.copyFrom(s)
 	return newSeg
 }
 
+// copyFrom copies elements and deletion marks of src, which must be of the same rank.
+func (s *segment[T]) copyFrom(src *segment[T]) {
+	copy(s.elements, src.elements)
+	if src.deleted != nil {
+		s.ensureDeletedBitmap()
+		copy(s.deleted, src.deleted)
+	} else {
+		clear(s.deleted)
+	}
+	s.deletedNum, s.minNonDeletedIdx, s.maxNonDeletedIdx = src.deletedNum, src.minNonDeletedIdx, src.maxNonDeletedIdx
+}
+
 func calculateWhiteSegmentsQuantity(capacity int) int {
 	if capacity < 0 {
 		panic("negative capacity")
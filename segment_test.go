@@ -1,6 +1,7 @@
 package bwarr
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,9 +19,9 @@ func Test_demoteSegment(t *testing.T) {
 	}{
 		{
 			name:     "demote 4 to 2",
-			from:     segment[int64]{elements: []int64{23, 0, 0, 42}, deleted: []bool{false, true, true, false}, deletedNum: 2},
-			to:       &segment[int64]{elements: []int64{16, 32}, deleted: []bool{true, true}, deletedNum: 2},
-			expected: &segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, false}, deletedNum: 0},
+			from:     segment[int64]{elements: []int64{23, 0, 0, 42}, deleted: delBitmap(false, true, true, false), deletedNum: 2},
+			to:       &segment[int64]{elements: []int64{16, 32}, deleted: delBitmap(true, true), deletedNum: 2},
+			expected: &segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, false), deletedNum: 0},
 		},
 	}
 	for _, tt := range tests { //nolint:paralleltest
@@ -88,38 +89,38 @@ func Test_mergeSegments(t *testing.T) {
 	}{
 		{
 			name:     "two elements",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: make([]bool, 4), maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: newDeletedBitmap(4), maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "rewind from first",
-			seg1:     segment[int64]{elements: []int64{3, 4}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{3, 4, 17, 37}, deleted: make([]bool, 4), maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{3, 4}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{3, 4, 17, 37}, deleted: newDeletedBitmap(4), maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "two with one deleted element",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, true}, deletedNum: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, true, false}, deletedNum: 1, maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, true), deletedNum: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, true, false), deletedNum: 1, maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "two with two deleted elements",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{true, false}, deletedNum: 1, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, true}, deletedNum: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, true, true, false}, deletedNum: 2, maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(true, false), deletedNum: 1, maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, true), deletedNum: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, true, true, false), deletedNum: 2, maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "if elements are equal, non-deleted must be first",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{true, false}, deletedNum: 1, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, true}, deletedNum: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{23, 23, 42, 42}, deleted: []bool{false, true, false, true}, deletedNum: 2, maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(true, false), deletedNum: 1, maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, true), deletedNum: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{23, 23, 42, 42}, deleted: delBitmap(false, true, false, true), deletedNum: 2, maxNonDeletedIdx: 2},
 		},
 	}
 	for _, tt := range tests { //nolint:paralleltest
@@ -128,7 +129,7 @@ func Test_mergeSegments(t *testing.T) {
 			// Copy seg2 into the second half of result
 			seg2Len := len(tt.seg2.elements)
 			copy(tt.result.elements[seg2Len:], tt.seg2.elements)
-			copy(tt.result.deleted[seg2Len:], tt.seg2.deleted)
+			copyDeletedBits(tt.result.deleted, seg2Len, tt.seg2.deleted, 0, seg2Len)
 			tt.result.deletedNum = tt.seg2.deletedNum
 			// Merge seg1 into result starting at position seg2Len
 			mergeSegments(&tt.seg1, tt.result, int64Cmp, seg2Len)
@@ -149,38 +150,38 @@ func Test_mergeSegmentsForDel(t *testing.T) {
 	}{
 		{
 			name:     "two elements",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: make([]bool, 4), maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: newDeletedBitmap(4), maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "rewind from first",
-			seg1:     segment[int64]{elements: []int64{3, 4}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{3, 4, 17, 37}, deleted: make([]bool, 4), maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{3, 4}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{3, 4, 17, 37}, deleted: newDeletedBitmap(4), maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "two with one deleted element",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, false}, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, true}, deletedNum: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, true, false}, deletedNum: 1, maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, false), maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, true), deletedNum: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, true, false), deletedNum: 1, maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "two with two deleted elements",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{true, false}, deletedNum: 1, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: []bool{false, true}, deletedNum: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, true, true, false}, deletedNum: 2, maxNonDeletedIdx: 3},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(true, false), deletedNum: 1, maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{17, 37}, deleted: delBitmap(false, true), deletedNum: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, true, true, false), deletedNum: 2, maxNonDeletedIdx: 3},
 		},
 		{
 			name:     "if elements are equal, non-deleted must be first",
-			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{true, false}, deletedNum: 1, maxNonDeletedIdx: 1},
-			seg2:     segment[int64]{elements: []int64{23, 42}, deleted: []bool{false, true}, deletedNum: 1},
-			result:   &segment[int64]{elements: make([]int64, 4), deleted: make([]bool, 4)},
-			expected: segment[int64]{elements: []int64{23, 23, 42, 42}, deleted: []bool{false, true, false, true}, deletedNum: 2, maxNonDeletedIdx: 2},
+			seg1:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(true, false), deletedNum: 1, maxNonDeletedIdx: 1},
+			seg2:     segment[int64]{elements: []int64{23, 42}, deleted: delBitmap(false, true), deletedNum: 1},
+			result:   &segment[int64]{elements: make([]int64, 4), deleted: newDeletedBitmap(4)},
+			expected: segment[int64]{elements: []int64{23, 23, 42, 42}, deleted: delBitmap(false, true, false, true), deletedNum: 2, maxNonDeletedIdx: 2},
 		},
 	}
 	for _, tt := range tests { //nolint:paralleltest
@@ -189,7 +190,7 @@ func Test_mergeSegmentsForDel(t *testing.T) {
 			// Copy seg2 into the second half of result
 			seg2Len := len(tt.seg2.elements)
 			copy(tt.result.elements[seg2Len:], tt.seg2.elements)
-			copy(tt.result.deleted[seg2Len:], tt.seg2.deleted)
+			copyDeletedBits(tt.result.deleted, seg2Len, tt.seg2.deleted, 0, seg2Len)
 			tt.result.deletedNum = tt.seg2.deletedNum
 			// Merge seg1 into result starting at position seg2Len
 			mergeSegmentsForDel(&tt.seg1, tt.result, int64Cmp, seg2Len)
@@ -209,13 +210,13 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 	}{
 		{
 			name: "one match",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  23,
 			want: 0,
 		},
 		{
 			name: "one not match",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  42,
 			want: -1,
 		},
@@ -223,7 +224,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "in the middle",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{false, false, false, false},
+				deleted:          delBitmap(false, false, false, false),
 				maxNonDeletedIdx: 3,
 			},
 			val:  23,
@@ -233,7 +234,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "in the beginning",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{false, false, false, false},
+				deleted:          delBitmap(false, false, false, false),
 				maxNonDeletedIdx: 3,
 			},
 			val:  17,
@@ -243,7 +244,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "in the end",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{false, false, false, false},
+				deleted:          delBitmap(false, false, false, false),
 				maxNonDeletedIdx: 3,
 			},
 			val:  42,
@@ -253,7 +254,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "with deleted",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{true, true, false, true},
+				deleted:          delBitmap(true, true, false, true),
 				maxNonDeletedIdx: 2,
 			},
 			val:  37,
@@ -263,7 +264,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "with deleted not match",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{false, true, false, false},
+				deleted:          delBitmap(false, true, false, false),
 				maxNonDeletedIdx: 3,
 			},
 			val:  23,
@@ -273,7 +274,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "with deleted postfix",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42, 49, 51, 69, 88},
-				deleted:          []bool{false, false, false, true, true, true, true, true},
+				deleted:          delBitmap(false, false, false, true, true, true, true, true),
 				maxNonDeletedIdx: 2,
 			},
 			val:  37,
@@ -283,7 +284,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "should find rightmost",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 23, 23, 37, 42, 49, 51},
-				deleted:          []bool{false, false, false, false, false, false, false, false},
+				deleted:          delBitmap(false, false, false, false, false, false, false, false),
 				maxNonDeletedIdx: 7,
 			},
 			val:  23,
@@ -293,7 +294,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "should find rightmost not deleted",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 23, 23, 37, 42, 49, 51},
-				deleted:          []bool{false, false, true, true, false, false, false, false},
+				deleted:          delBitmap(false, false, true, true, false, false, false, false),
 				maxNonDeletedIdx: 7,
 			},
 			val:  23,
@@ -303,7 +304,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 			name: "should find rightmost not deleted in the middle",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 23, 23, 37, 42, 49, 51},
-				deleted:          []bool{false, true, false, true, false, false, false, false},
+				deleted:          delBitmap(false, true, false, true, false, false, false, false),
 				maxNonDeletedIdx: 7,
 			},
 			val:  23,
@@ -314,6 +315,7 @@ func Test_findRightmostNotDeleted(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			assert.Equalf(t, tt.want, tt.seg.findRightmostNotDeleted(int64Cmp, tt.val), "searchInSegment(%v, %v)", tt.seg, tt.val)
+			assert.Equal(t, tt.want, findRightmostNotDeletedOrdered(&tt.seg, tt.val), "findRightmostNotDeletedOrdered")
 		})
 	}
 }
@@ -329,43 +331,43 @@ func Test_segment_findGTOE(t *testing.T) {
 	}{
 		{
 			name: "one match",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  23,
 			want: 0,
 		},
 		{
 			name: "one greater",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  11,
 			want: 0,
 		},
 		{
 			name: "first",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  11,
 			want: 0,
 		},
 		{
 			name: "last",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  42,
 			want: 3,
 		},
 		{
 			name: "in the middle",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  30,
 			want: 2,
 		},
 		{
 			name: "in the middle with deleted",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, true, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, true, false), maxNonDeletedIdx: 3},
 			val:  30,
 			want: 3,
 		},
 		{
 			name: "all less",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false)},
 			val:  101,
 			want: -1,
 		},
@@ -390,55 +392,55 @@ func Test_segment_findLess(t *testing.T) {
 	}{
 		{
 			name: "one equal",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  23,
 			want: -1,
 		},
 		{
 			name: "one less",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  42,
 			want: 0,
 		},
 		{
 			name: "one greater",
-			seg:  segment[int64]{elements: []int64{23}, deleted: []bool{false}},
+			seg:  segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
 			val:  11,
 			want: -1,
 		},
 		{
 			name: "last",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  77,
 			want: 3,
 		},
 		{
 			name: "first",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  11,
 			want: -1,
 		},
 		{
 			name: "last",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  77,
 			want: 3,
 		},
 		{
 			name: "in the middle",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, false, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
 			val:  30,
 			want: 1,
 		},
 		{
 			name: "in the middle with deleted",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{false, true, false, false}, maxNonDeletedIdx: 3},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, true, false, false), maxNonDeletedIdx: 3},
 			val:  30,
 			want: 0,
 		},
 		{
 			name: "all deleted",
-			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: []bool{true, true, true, true}, maxNonDeletedIdx: -1},
+			seg:  segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(true, true, true, true), maxNonDeletedIdx: -1},
 			val:  23,
 			want: -1,
 		},
@@ -452,11 +454,76 @@ func Test_segment_findLess(t *testing.T) {
 	}
 }
 
+//nolint:exhaustruct
+func Test_segment_findGTAndLTOE(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		seg      segment[int64]
+		val      int64
+		wantGT   int
+		wantLTOE int
+	}{
+		{
+			name:     "one equal",
+			seg:      segment[int64]{elements: []int64{23}, deleted: delBitmap(false)},
+			val:      23,
+			wantGT:   -1,
+			wantLTOE: 0,
+		},
+		{
+			name:     "equal in the middle",
+			seg:      segment[int64]{elements: []int64{17, 23, 23, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
+			val:      23,
+			wantGT:   3,
+			wantLTOE: 2,
+		},
+		{
+			name:     "equal deleted",
+			seg:      segment[int64]{elements: []int64{17, 23, 23, 42}, deleted: delBitmap(false, false, true, false), maxNonDeletedIdx: 3},
+			val:      23,
+			wantGT:   3,
+			wantLTOE: 1,
+		},
+		{
+			name:     "not present",
+			seg:      segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
+			val:      30,
+			wantGT:   2,
+			wantLTOE: 1,
+		},
+		{
+			name:     "out of range",
+			seg:      segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(false, false, false, false), maxNonDeletedIdx: 3},
+			val:      42,
+			wantGT:   -1,
+			wantLTOE: 3,
+		},
+		{
+			name:     "all deleted",
+			seg:      segment[int64]{elements: []int64{17, 23, 37, 42}, deleted: delBitmap(true, true, true, true), maxNonDeletedIdx: -1},
+			val:      23,
+			wantGT:   -1,
+			wantLTOE: -1,
+		},
+	}
+
+	for _, tt := range tests { //nolint:paralleltest
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.wantGT, tt.seg.findGT(int64Cmp, tt.val), "findGT")
+			assert.Equal(t, tt.wantLTOE, tt.seg.findLTOE(int64Cmp, tt.val), "findLTOE")
+			assert.Equal(t, tt.wantGT, findFirstGreaterOrdered(&tt.seg, tt.val, false), "findFirstGreaterOrdered")
+			assert.Equal(t, tt.wantLTOE, findLastLessOrdered(&tt.seg, tt.val, true), "findLastLessOrdered")
+		})
+	}
+}
+
 func Test_segment_nextNonDeletedAfter(t *testing.T) {
 	t.Parallel()
 	seg := segment[int64]{ // nolint:exhaustruct
 		elements:         []int64{17, 23, 23, 23, 37, 42, 49, 51},
-		deleted:          []bool{false, true, false, true, false, false, false, false},
+		deleted:          delBitmap(false, true, false, true, false, false, false, false),
 		maxNonDeletedIdx: 7,
 	}
 	tests := []struct {
@@ -508,11 +575,85 @@ func Test_segment_nextNonDeletedAfter(t *testing.T) {
 	}
 }
 
+func Test_segment_nthNonDeletedDownFrom(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(43)) //nolint:gosec
+	// Several words of deleted flags.
+	flags := make([]bool, 200)
+	for i := range flags {
+		flags[i] = r.Intn(3) == 0
+	}
+	seg := segment[int64]{elements: make([]int64, len(flags)), deleted: delBitmap(flags...)} //nolint:exhaustruct
+	for last := range flags {
+		var nonDeleted []int // Indexes going down from last.
+		for i := last; i >= 0; i-- {
+			if !flags[i] {
+				nonDeleted = append(nonDeleted, i)
+			}
+		}
+		for n := range len(nonDeleted) + 2 {
+			want := -1
+			if n < len(nonDeleted) {
+				want = nonDeleted[n]
+			}
+			require.Equal(t, want, seg.nthNonDeletedDownFrom(last, n), "last=%d n=%d", last, n)
+		}
+	}
+}
+
+func Test_segment_skipDeletedAcrossWords(t *testing.T) {
+	t.Parallel()
+	const l = 200
+	seg := segment[int64]{elements: make([]int64, l), deleted: newDeletedBitmap(l)} //nolint:exhaustruct
+	for i := range l {
+		if i != 3 && i != 64 && i != 130 {
+			seg.markDeleted(i)
+		}
+	}
+	assert.Equal(t, 3, seg.nextNonDeletedAfter(-1))
+	assert.Equal(t, 64, seg.nextNonDeletedAfter(3))
+	assert.Equal(t, 130, seg.nextNonDeletedAfter(64))
+	assert.Equal(t, l, seg.nextNonDeletedAfter(130))
+	assert.Equal(t, 130, seg.prevNonDeletedBefore(l))
+	assert.Equal(t, 64, seg.prevNonDeletedBefore(130))
+	assert.Equal(t, 3, seg.prevNonDeletedBefore(64))
+	assert.Equal(t, -1, seg.prevNonDeletedBefore(3))
+}
+
+func Test_copyDeletedBits(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(44)) //nolint:gosec
+	const l = 300
+	for range 2000 {
+		src, dst := make([]bool, l), make([]bool, l)
+		for i := range l {
+			src[i], dst[i] = r.Intn(2) == 0, r.Intn(2) == 0
+		}
+		n := r.Intn(l)
+		srcIdx, dstIdx := r.Intn(l-n+1), r.Intn(l-n+1)
+		expected := append(append(append([]bool{}, dst[:dstIdx]...), src[srcIdx:srcIdx+n]...), dst[dstIdx+n:]...)
+		bitmap := delBitmap(dst...)
+		copyDeletedBits(bitmap, dstIdx, delBitmap(src...), srcIdx, n)
+		require.Equal(t, delBitmap(expected...), bitmap, "srcIdx=%d dstIdx=%d n=%d", srcIdx, dstIdx, n)
+
+		// Overlapping ranges within one bitmap, as merges move flags to the beginning of a segment.
+		dstIdx = r.Intn(srcIdx + 1)
+		expected = append(append(append([]bool{}, src[:dstIdx]...), src[srcIdx:srcIdx+n]...), src[dstIdx+n:]...)
+		bitmap = delBitmap(src...)
+		copyDeletedBits(bitmap, dstIdx, bitmap, srcIdx, n)
+		require.Equal(t, delBitmap(expected...), bitmap, "overlapping srcIdx=%d dstIdx=%d n=%d", srcIdx, dstIdx, n)
+	}
+
+	bitmap := delBitmap(true, true, true, true)
+	copyDeletedBits(bitmap, 1, nil, 0, 2) // Nil bitmap of a clean segment has all flags unset.
+	assert.Equal(t, delBitmap(true, false, false, true), bitmap)
+}
+
 func Test_segment_AllDeleted(t *testing.T) {
 	t.Parallel()
 	seg := segment[int64]{ // nolint:exhaustruct
 		elements: []int64{17, 23, 42, 51},
-		deleted:  []bool{true, true, true, true},
+		deleted:  delBitmap(true, true, true, true),
 	}
 	assert.Equal(t, -1, seg.minNonDeletedIndex())
 	assert.Equal(t, -1, seg.maxNonDeletedIndex())
@@ -530,7 +671,7 @@ func Test_segment_min(t *testing.T) {
 			name: "single element",
 			seg: segment[int64]{
 				elements: []int64{42},
-				deleted:  []bool{false},
+				deleted:  delBitmap(false),
 			},
 			want: 0,
 		},
@@ -538,7 +679,7 @@ func Test_segment_min(t *testing.T) {
 			name: "two elements - first is min",
 			seg: segment[int64]{
 				elements:         []int64{17, 42},
-				deleted:          []bool{false, false},
+				deleted:          delBitmap(false, false),
 				maxNonDeletedIdx: 1,
 			},
 			want: 0,
@@ -547,7 +688,7 @@ func Test_segment_min(t *testing.T) {
 			name: "two equal elements - should return rightmost (FIFO)",
 			seg: segment[int64]{
 				elements:         []int64{23, 23},
-				deleted:          []bool{false, false},
+				deleted:          delBitmap(false, false),
 				maxNonDeletedIdx: 1,
 			},
 			want: 1,
@@ -556,7 +697,7 @@ func Test_segment_min(t *testing.T) {
 			name: "three equal elements - should return rightmost",
 			seg: segment[int64]{
 				elements:         []int64{23, 23, 23},
-				deleted:          []bool{false, false, false},
+				deleted:          delBitmap(false, false, false),
 				maxNonDeletedIdx: 2,
 			},
 			want: 2,
@@ -565,7 +706,7 @@ func Test_segment_min(t *testing.T) {
 			name: "equal elements with deleted after - should return last non-deleted",
 			seg: segment[int64]{
 				elements:         []int64{23, 23, 23},
-				deleted:          []bool{false, false, true},
+				deleted:          delBitmap(false, false, true),
 				maxNonDeletedIdx: 2,
 			},
 			want: 1,
@@ -574,7 +715,7 @@ func Test_segment_min(t *testing.T) {
 			name: "equal elements with multiple deleted after - should return last non-deleted",
 			seg: segment[int64]{
 				elements:         []int64{23, 23, 23, 23},
-				deleted:          []bool{false, false, true, true},
+				deleted:          delBitmap(false, false, true, true),
 				maxNonDeletedIdx: 3,
 			},
 			want: 1,
@@ -583,7 +724,7 @@ func Test_segment_min(t *testing.T) {
 			name: "sorted array - minimum is first",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{false, false, false, false},
+				deleted:          delBitmap(false, false, false, false),
 				maxNonDeletedIdx: 3,
 			},
 			want: 0,
@@ -592,7 +733,7 @@ func Test_segment_min(t *testing.T) {
 			name: "sorted array with equal minimums",
 			seg: segment[int64]{
 				elements:         []int64{17, 17, 23, 37, 42},
-				deleted:          []bool{false, false, false, false, false},
+				deleted:          delBitmap(false, false, false, false, false),
 				maxNonDeletedIdx: 4,
 			},
 			want: 1,
@@ -601,7 +742,7 @@ func Test_segment_min(t *testing.T) {
 			name: "sorted array with equal minimums and deleted after",
 			seg: segment[int64]{
 				elements:         []int64{17, 17, 17, 23, 37, 42},
-				deleted:          []bool{false, false, true, false, false, false},
+				deleted:          delBitmap(false, false, true, false, false, false),
 				maxNonDeletedIdx: 5,
 			},
 			want: 1,
@@ -610,7 +751,7 @@ func Test_segment_min(t *testing.T) {
 			name: "sorted array with larger elements after equal mins",
 			seg: segment[int64]{
 				elements:         []int64{5, 5, 5, 10, 20, 30},
-				deleted:          []bool{false, false, false, false, false, false},
+				deleted:          delBitmap(false, false, false, false, false, false),
 				maxNonDeletedIdx: 5,
 			},
 			want: 2,
@@ -619,7 +760,7 @@ func Test_segment_min(t *testing.T) {
 			name: "first element deleted - min is second",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{true, false, false, false},
+				deleted:          delBitmap(true, false, false, false),
 				minNonDeletedIdx: 1,
 				maxNonDeletedIdx: 3,
 			},
@@ -629,7 +770,7 @@ func Test_segment_min(t *testing.T) {
 			name: "sparse deleted elements",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{true, false, true, false},
+				deleted:          delBitmap(true, false, true, false),
 				minNonDeletedIdx: 1,
 				maxNonDeletedIdx: 3,
 			},
@@ -639,7 +780,7 @@ func Test_segment_min(t *testing.T) {
 			name: "all equal non-deleted with deleted suffix",
 			seg: segment[int64]{
 				elements:         []int64{10, 10, 10, 10, 10},
-				deleted:          []bool{false, false, false, true, true},
+				deleted:          delBitmap(false, false, false, true, true),
 				maxNonDeletedIdx: 4,
 			},
 			want: 2,
@@ -648,7 +789,7 @@ func Test_segment_min(t *testing.T) {
 			name: "complex case - equal mins, then deleted, then larger values",
 			seg: segment[int64]{
 				elements:         []int64{5, 5, 5, 5, 10, 15, 20},
-				deleted:          []bool{false, false, false, true, false, false, false},
+				deleted:          delBitmap(false, false, false, true, false, false, false),
 				maxNonDeletedIdx: 6,
 			},
 			want: 2,
@@ -657,7 +798,7 @@ func Test_segment_min(t *testing.T) {
 			name: "single non-deleted in middle of deleted",
 			seg: segment[int64]{
 				elements:         []int64{17, 23, 37, 42},
-				deleted:          []bool{true, true, false, true},
+				deleted:          delBitmap(true, true, false, true),
 				minNonDeletedIdx: 2,
 				maxNonDeletedIdx: 2,
 			},
@@ -667,7 +808,7 @@ func Test_segment_min(t *testing.T) {
 			name: "equal elements at start with larger after",
 			seg: segment[int64]{
 				elements:         []int64{1, 1, 2, 3, 4},
-				deleted:          []bool{false, false, false, false, false},
+				deleted:          delBitmap(false, false, false, false, false),
 				maxNonDeletedIdx: 4,
 			},
 			want: 1,
@@ -676,7 +817,7 @@ func Test_segment_min(t *testing.T) {
 			name: "invariant test - deleted only after equal non-deleted",
 			seg: segment[int64]{
 				elements:         []int64{10, 10, 10, 10, 20, 30},
-				deleted:          []bool{false, false, true, true, false, false},
+				deleted:          delBitmap(false, false, true, true, false, false),
 				maxNonDeletedIdx: 5,
 			},
 			want: 1,
@@ -690,25 +831,27 @@ func Test_segment_min(t *testing.T) {
 			assert.Equalf(t, tt.want, got, "min() returned index %d, want %d", got, tt.want)
 
 			// Verify the returned index is not deleted
-			if got >= 0 && got < len(tt.seg.deleted) {
-				assert.Falsef(t, tt.seg.deleted[got], "min() returned deleted element at index %d", got)
+			if got >= 0 && got < len(tt.seg.elements) {
+				assert.Falsef(t, isDeletedBit(tt.seg.deleted, got), "min() returned deleted element at index %d", got)
 			}
 		})
 	}
 }
 
 func validateSegment[T any](t *testing.T, seg segment[T], cmp CmpFunc[T]) {
-	require.Len(t, seg.elements, len(seg.deleted))
+	if seg.deleted != nil {
+		require.Len(t, seg.deleted, len(newDeletedBitmap(len(seg.elements))))
+	}
 	deleted, firstNonDelIdx, lastNonDelIdx := 0, 0, len(seg.elements)-1
 	metNonDel := false
 	for i := range seg.elements {
-		if seg.deleted[i] {
+		if isDeletedBit(seg.deleted, i) {
 			deleted++
 			continue
 		}
 		// If elements are equal, deleted must be after non-deleted;
 		if i != 0 && cmp(seg.elements[i-1], seg.elements[i]) == 0 {
-			if seg.deleted[i-1] {
+			if isDeletedBit(seg.deleted, i-1) {
 				assert.Failf(t, "Order constraint", "at index %d and %d: equal elements %d, but deleted comes before non-deleted", i-1, i, seg.elements[i])
 			}
 		}
@@ -718,7 +861,7 @@ func validateSegment[T any](t *testing.T, seg segment[T], cmp CmpFunc[T]) {
 			metNonDel = true
 		}
 
-		if i >= len(seg.elements)-1 || seg.deleted[i+1] {
+		if i >= len(seg.elements)-1 || isDeletedBit(seg.deleted, i+1) {
 			continue
 		}
 		assert.LessOrEqual(t, cmp(seg.elements[i], seg.elements[i+1]), 0)
@@ -729,16 +872,23 @@ func validateSegment[T any](t *testing.T, seg segment[T], cmp CmpFunc[T]) {
 }
 
 func segmentsEqual[T any](t *testing.T, expected, actual segment[T]) {
-	require.Len(t, expected.elements, len(expected.deleted))
 	require.Len(t, actual.elements, len(expected.elements))
-	require.Len(t, actual.deleted, len(expected.deleted))
 	require.Equal(t, expected.deletedNum, actual.deletedNum)
 	for i := range expected.elements {
-		assert.Equal(t, expected.deleted[i], actual.deleted[i])
-		if !expected.deleted[i] {
+		assert.Equal(t, isDeletedBit(expected.deleted, i), isDeletedBit(actual.deleted, i))
+		if !isDeletedBit(expected.deleted, i) {
 			assert.Equal(t, expected.elements[i], actual.elements[i])
 		}
 	}
 	require.Equal(t, expected.minNonDeletedIdx, actual.minNonDeletedIdx)
 	require.Equal(t, expected.maxNonDeletedIdx, actual.maxNonDeletedIdx)
 }
+
+// delBitmap packs deletion flags of a segment into a bitmap.
+func delBitmap(flags ...bool) []uint64 {
+	bitmap := newDeletedBitmap(len(flags))
+	for i, f := range flags {
+		setDeletedBit(bitmap, i, f)
+	}
+	return bitmap
+}
@@ -0,0 +1,91 @@
+package bwarr
+
+import "unsafe"
+
+// Stats describes the internal layout of a BWArr. It is intended for tuning and monitoring,
+// e.g., to decide when to call Compact or Rebuild, or to export the numbers to a metrics system.
+type Stats struct {
+	Len            int            // Number of elements, as returned by Len.
+	Total          int            // Number of elements including lazy-deleted ones.
+	Segments       []SegmentStats // Per-rank information, index is the rank of the segment.
+	BytesAllocated int            // Memory held by segments, including inactive ones, filters, cascade levels and search indexes.
+	TombstoneRatio float64        // Share of lazy-deleted elements in Total, 0 for an empty BWArr.
+	Merges         uint64         // Number of segment merges since creation.
+	Demotions      uint64         // Number of segment demotions since creation.
+	// Number of comparisons since creation. Counted only if Options.CountComparisons is set.
+	Comparisons uint64
+}
+
+// SegmentStats describes a segment of a BWArr.
+type SegmentStats struct {
+	Rank      int  // Segment holds 2^Rank elements.
+	Allocated bool // Memory for the segment is allocated.
+	Active    bool // Segment holds elements.
+	Live      int  // Number of non-deleted elements.
+	Deleted   int  // Number of lazy-deleted elements.
+	// Indexes of the first and the last non-deleted elements, -1 if there are no such elements.
+	MinNonDeletedIdx int
+	MaxNonDeletedIdx int
+}
+
+// Stats returns the current layout of the BWArr and the counters of internal operations.
+// The operation has O(log N) time complexity.
+func (bwa *BWArr[T]) Stats() Stats {
+	stats := Stats{ //nolint:exhaustruct
+		Len:       bwa.Len(),
+		Total:     bwa.total,
+		Segments:  make([]SegmentStats, len(bwa.whiteSegments)),
+		Merges:    bwa.merges,
+		Demotions: bwa.demotions,
+	}
+	ext := bwa.ext
+	if ext != nil && ext.cmpCounter != nil {
+		stats.Comparisons = ext.cmpCounter.n
+	}
+	if bwa.total > 0 {
+		stats.TombstoneRatio = float64(bwa.total-stats.Len) / float64(bwa.total)
+	}
+	stats.BytesAllocated = len(bwa.whiteSegments) * int(unsafe.Sizeof(segment[T]{})) //nolint:exhaustruct
+	if ext != nil && ext.bloom != nil {
+		stats.BytesAllocated += ext.bloom.allocatedBytes()
+	}
+	if ext != nil && ext.cascade != nil {
+		stats.BytesAllocated += ext.cascade.allocatedBytes()
+	}
+	if ext != nil && ext.index != nil {
+		stats.BytesAllocated += ext.index.allocatedBytes()
+	}
+
+	for rank := range bwa.whiteSegments {
+		seg := &bwa.whiteSegments[rank]
+		ss := SegmentStats{
+			Rank:             rank,
+			Allocated:        len(seg.elements) > 0,
+			Active:           bwa.total&(1<<rank) != 0,
+			MinNonDeletedIdx: -1,
+			MaxNonDeletedIdx: -1,
+		}
+		stats.BytesAllocated += seg.allocatedBytes()
+		if ss.Active {
+			ss.Deleted = seg.deletedNum
+			ss.Live = len(seg.elements) - seg.deletedNum
+			if ss.Live > 0 {
+				ss.MinNonDeletedIdx = seg.nextNonDeletedAfter(seg.minNonDeletedIdx - 1)
+				ss.MaxNonDeletedIdx = seg.prevNonDeletedBefore(seg.maxNonDeletedIdx + 1)
+			}
+		}
+		stats.Segments[rank] = ss
+	}
+	return stats
+}
+
+// comparisonCounter wraps a comparison function to count its calls.
+type comparisonCounter[T any] struct {
+	cmp CmpFunc[T]
+	n   uint64
+}
+
+func (c *comparisonCounter[T]) compare(a, b T) int {
+	c.n++
+	return c.cmp(a, b)
+}
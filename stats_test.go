@@ -0,0 +1,68 @@
+package bwarr
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBWArr_Stats(t *testing.T) {
+	t.Parallel()
+	bwa := New(int64Cmp, 0)
+	stats := bwa.Stats()
+	assert.Equal(t, 0, stats.Len)
+	assert.Empty(t, stats.Segments)
+	assert.Zero(t, stats.TombstoneRatio)
+
+	for i := range int64(7) {
+		bwa.Insert(i)
+	}
+	bwa.Delete(3) // Segments: {6}, {4, 5}, {0, 1, 2, 3}.
+
+	stats = bwa.Stats()
+	assert.Equal(t, 6, stats.Len)
+	assert.Equal(t, 7, stats.Total)
+	assert.InDelta(t, 1.0/7, stats.TombstoneRatio, 1e-9)
+	// Insertions of 2, 4, 6 and 7 merge 1 + 2 + 1 + 0 segments.
+	assert.Equal(t, uint64(4), stats.Merges)
+	assert.Equal(t, uint64(0), stats.Demotions)
+	assert.Equal(t, uint64(0), stats.Comparisons, "comparisons are not counted by default")
+	require.Len(t, stats.Segments, 3)
+	assert.Equal(t, SegmentStats{Rank: 0, Allocated: true, Active: true, Live: 1, Deleted: 0, MinNonDeletedIdx: 0, MaxNonDeletedIdx: 0}, stats.Segments[0])
+	assert.Equal(t, SegmentStats{Rank: 1, Allocated: true, Active: true, Live: 2, Deleted: 0, MinNonDeletedIdx: 0, MaxNonDeletedIdx: 1}, stats.Segments[1])
+	assert.Equal(t, SegmentStats{Rank: 2, Allocated: true, Active: true, Live: 3, Deleted: 1, MinNonDeletedIdx: 0, MaxNonDeletedIdx: 2}, stats.Segments[2])
+	segsSize := 3 * int(unsafe.Sizeof(segment[int64]{})) //nolint:exhaustruct
+	assert.Equal(t, segsSize+7*8+1*8, stats.BytesAllocated, "only the segment with a deleted element has a bitmap")
+
+	bwa.Delete(2) // Half of the segment of rank 2 is deleted, it is merged with the segment of rank 1.
+	stats = bwa.Stats()
+	assert.Equal(t, uint64(5), stats.Merges)
+	assert.False(t, stats.Segments[1].Active)
+
+	bwa = NewFromSlice(int64Cmp, []int64{1, 2, 3, 4})
+	bwa.Delete(1)
+	bwa.Delete(2) // Half of the segment of rank 2 is deleted, it is demoted to rank 1.
+	stats = bwa.Stats()
+	assert.Equal(t, uint64(1), stats.Demotions)
+	assert.True(t, stats.Segments[1].Active)
+	assert.False(t, stats.Segments[2].Active)
+}
+
+func TestBWArr_StatsComparisons(t *testing.T) {
+	t.Parallel()
+	bwa := NewWithOptions(int64Cmp, 0, Options{CountComparisons: true}) //nolint:exhaustruct
+	bwa.Insert(1)
+	assert.Equal(t, uint64(0), bwa.Stats().Comparisons)
+	bwa.Insert(2)
+	assert.Equal(t, uint64(1), bwa.Stats().Comparisons)
+	bwa.Has(2)
+	n := bwa.Stats().Comparisons
+	assert.Greater(t, n, uint64(1))
+
+	clone := bwa.Clone()
+	clone.Has(1)
+	assert.Equal(t, n, bwa.Stats().Comparisons, "clone must have its own counter")
+	assert.Greater(t, clone.Stats().Comparisons, n)
+}
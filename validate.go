@@ -0,0 +1,123 @@
+package bwarr
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// ErrInvariantViolated is returned by Validate when the internal state of a BWArr is inconsistent.
+var ErrInvariantViolated = errors.New("bwarr: invariant violated")
+
+// Validate checks the internal invariants of the BWArr and returns an error wrapping
+// ErrInvariantViolated that describes the first violation found, or nil.
+// It is intended for debugging and fuzzing, e.g., to tell an inconsistent comparison
+// function from a bug in the library. The operation has O(N*Log^2(N)) time complexity in the worst case.
+//
+// Checked invariants:
+//   - every active segment is allocated and has 2^rank elements;
+//   - elements of every segment are sorted, including the lazy-deleted ones;
+//   - equal elements in a segment have deleted ones after non-deleted ones;
+//   - deletedNum matches the deletion flags and is less than a half of the segment;
+//   - minNonDeletedIdx and maxNonDeletedIdx are the indexes of the first and the last non-deleted elements;
+//   - no lazy-deleted element has an equal non-deleted one in a higher rank: deletions take
+//     the oldest of equal elements, and older elements are kept in higher ranks.
+//
+// The age order of non-deleted equal elements is not checked, since the BWArr does not store
+// the insertion order explicitly.
+func (bwa *BWArr[T]) Validate() error {
+	if bwa.total < 0 {
+		return fmt.Errorf("%w: negative total %d", ErrInvariantViolated, bwa.total)
+	}
+	if maxRank := bwa.maxRank(); maxRank >= len(bwa.whiteSegments) {
+		return fmt.Errorf("%w: total %d needs segment of rank %d, but there are only %d segments",
+			ErrInvariantViolated, bwa.total, maxRank, len(bwa.whiteSegments))
+	}
+	for rank := range bwa.whiteSegments {
+		if bwa.total&(1<<rank) == 0 {
+			continue
+		}
+		if err := bwa.whiteSegments[rank].validate(bwa.cmp); err != nil {
+			return fmt.Errorf("%w: segment of rank %d: %w", ErrInvariantViolated, rank, err)
+		}
+	}
+	return bwa.validateFIFO()
+}
+
+// validateFIFO checks that no lazy-deleted element has an equal non-deleted element in a higher rank,
+// i.e., that a newer element has not been deleted while an older equal one is still live.
+// Segments must be validated before.
+func (bwa *BWArr[T]) validateFIFO() error {
+	for rank := range bwa.whiteSegments {
+		seg := &bwa.whiteSegments[rank]
+		if bwa.total&(1<<rank) == 0 || seg.deletedNum == 0 {
+			continue
+		}
+		for i := range seg.elements {
+			if !isDeletedBit(seg.deleted, i) {
+				continue
+			}
+			if i > 0 && isDeletedBit(seg.deleted, i-1) && bwa.cmp(seg.elements[i-1], seg.elements[i]) == 0 {
+				continue // Equal deleted elements are adjacent, one check covers all of them.
+			}
+			for higher := rank + 1; higher < len(bwa.whiteSegments); higher++ {
+				if bwa.total&(1<<higher) == 0 {
+					continue
+				}
+				if j := bwa.whiteSegments[higher].findRightmostNotDeleted(bwa.cmp, seg.elements[i]); j >= 0 {
+					return fmt.Errorf("%w: deleted element at %d of rank %d has older non-deleted equal element at %d of rank %d",
+						ErrInvariantViolated, i, rank, j, higher)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *segment[T]) validate(cmp CmpFunc[T]) error {
+	l := len(s.elements)
+	rank := bits.Len(uint(l)) - 1
+	switch {
+	case l == 0:
+		return errors.New("segment is not allocated")
+	case l&(l-1) != 0:
+		return fmt.Errorf("length %d is not a power of two", l)
+	case s.deleted != nil && len(s.deleted) != len(newDeletedBitmap(l)):
+		return fmt.Errorf("deletion bitmap has %d words for %d elements", len(s.deleted), l)
+	}
+
+	deleted, firstLive, lastLive := 0, -1, -1
+	for i := range l {
+		del := isDeletedBit(s.deleted, i)
+		if del {
+			deleted++
+		} else {
+			if firstLive < 0 {
+				firstLive = i
+			}
+			lastLive = i
+		}
+		if i == 0 {
+			continue
+		}
+		c := cmp(s.elements[i-1], s.elements[i])
+		if c > 0 {
+			return fmt.Errorf("elements at %d and %d are not sorted: %v > %v", i-1, i, s.elements[i-1], s.elements[i])
+		}
+		if c == 0 && !del && isDeletedBit(s.deleted, i-1) {
+			return fmt.Errorf("deleted element at %d is before equal non-deleted element at %d", i-1, i)
+		}
+	}
+
+	switch {
+	case deleted != s.deletedNum:
+		return fmt.Errorf("deletedNum is %d, but %d elements are marked deleted", s.deletedNum, deleted)
+	case rank == 0 && deleted != 0, rank > 0 && deleted >= l/2:
+		return fmt.Errorf("%d of %d elements are deleted, segment had to be demoted or merged", deleted, l)
+	case s.minNonDeletedIdx != firstLive:
+		return fmt.Errorf("minNonDeletedIdx is %d, but the first non-deleted element is at %d", s.minNonDeletedIdx, firstLive)
+	case s.maxNonDeletedIdx != lastLive:
+		return fmt.Errorf("maxNonDeletedIdx is %d, but the last non-deleted element is at %d", s.maxNonDeletedIdx, lastLive)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package bwarr
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBWArr_ValidateRandomOperations(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(1))
+	bwa := New(int64Cmp, 0)
+	for range 20000 {
+		v := int64(r.Intn(100))
+		switch r.Intn(8) {
+		case 0:
+			bwa.DeleteMin()
+		case 1:
+			bwa.DeleteMax()
+		case 2, 3:
+			bwa.Delete(v)
+		case 4:
+			bwa.ReplaceOrInsert(v)
+		default:
+			bwa.Insert(v)
+		}
+		require.NoError(t, bwa.Validate())
+	}
+	bwa.Rebuild()
+	require.NoError(t, bwa.Validate())
+}
+
+func TestBWArr_ValidateDetectsCorruption(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		corrupt func(bwa *BWArr[int64])
+	}{
+		{
+			name:    "total needs missing segment",
+			corrupt: func(bwa *BWArr[int64]) { bwa.total = 1 << len(bwa.whiteSegments) },
+		},
+		{
+			name:    "unallocated active segment",
+			corrupt: func(bwa *BWArr[int64]) { bwa.whiteSegments[2] = segment[int64]{} }, //nolint:exhaustruct
+		},
+		{
+			name:    "unsorted segment",
+			corrupt: func(bwa *BWArr[int64]) { bwa.whiteSegments[2].elements[0] = 100 },
+		},
+		{
+			name: "deleted before equal non-deleted",
+			corrupt: func(bwa *BWArr[int64]) {
+				seg := &bwa.whiteSegments[2]
+				seg.elements[1] = seg.elements[0]
+				seg.markDeleted(0)
+				seg.deletedNum++
+			},
+		},
+		{
+			name:    "wrong deletedNum",
+			corrupt: func(bwa *BWArr[int64]) { bwa.whiteSegments[2].deletedNum = 1 },
+		},
+		{
+			name: "too many deleted",
+			corrupt: func(bwa *BWArr[int64]) {
+				seg := &bwa.whiteSegments[2]
+				seg.markDeleted(1)
+				seg.markDeleted(2)
+				seg.deletedNum += 2
+			},
+		},
+		{
+			name:    "wrong minNonDeletedIdx",
+			corrupt: func(bwa *BWArr[int64]) { bwa.whiteSegments[2].minNonDeletedIdx = 1 },
+		},
+		{
+			name:    "wrong maxNonDeletedIdx",
+			corrupt: func(bwa *BWArr[int64]) { bwa.whiteSegments[2].maxNonDeletedIdx = 2 },
+		},
+	}
+	for _, tt := range tests { //nolint:paralleltest
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			bwa := New(int64Cmp, 0)
+			for i := range int64(7) {
+				bwa.Insert(i)
+			}
+			require.NoError(t, bwa.Validate())
+			tt.corrupt(bwa)
+			require.ErrorIs(t, bwa.Validate(), ErrInvariantViolated)
+		})
+	}
+}
+
+func TestBWArr_ValidateDetectsDeletedNewerElement(t *testing.T) {
+	t.Parallel()
+	bwa := New(int64Cmp, 0)
+	for i := range int64(15) { // Ranks 3, 2, 1, 0 hold 0..7, 8..11, 12..13, 14.
+		bwa.Insert(i)
+	}
+	bwa.Delete(8)
+	require.NoError(t, bwa.Validate())
+
+	seg := &bwa.whiteSegments[2]
+	seg.elements[0] = 7 // Now equal to the non-deleted last element of rank 3, but deleted.
+	err := bwa.Validate()
+	require.ErrorIs(t, err, ErrInvariantViolated)
+	require.ErrorContains(t, err, "older non-deleted equal element")
+}